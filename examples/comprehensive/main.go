@@ -105,7 +105,7 @@ func main() {
 	// Print summary of all metering fields that should have been transmitted
 	fmt.Println("Expected Metering Payload Fields:")
 	fmt.Println("==================================")
-	printExpectedMeteringFields(metadata, result)
+	printExpectedMeteringFields(client, metadata, result)
 
 	fmt.Println()
 	fmt.Println("Metering data sent asynchronously to Revenium.")
@@ -284,7 +284,7 @@ func printMetadata(m *revenium.UsageMetadata) {
 }
 
 // printExpectedMeteringFields shows all fields that should appear in the metering payload
-func printExpectedMeteringFields(m *revenium.UsageMetadata, result *revenium.VideoGenerationResult) {
+func printExpectedMeteringFields(client *revenium.ReveniumRunway, m *revenium.UsageMetadata, result *revenium.VideoGenerationResult) {
 	fmt.Println()
 	fmt.Println("=== MIDDLEWARE-POPULATED FIELDS ===")
 	fmt.Println("(Automatically set by the middleware)")
@@ -337,58 +337,10 @@ func printExpectedMeteringFields(m *revenium.UsageMetadata, result *revenium.Vid
 	fmt.Printf("  subscriber:               (%d nested fields)\n", len(m.Subscriber))
 	fmt.Printf("  custom:                   (%d nested fields, merged at top level)\n", len(m.Custom))
 
-	// Generate expected payload for verification
+	// Build the payload with the middleware's own builder for verification
 	fmt.Println()
-	fmt.Println("=== EXPECTED METERING PAYLOAD (JSON preview) ===")
-	payload := buildExpectedPayload(m, result)
+	fmt.Println("=== METERING PAYLOAD (JSON preview) ===")
+	payload := client.BuildMeteringPayload(result, m)
 	jsonBytes, _ := json.MarshalIndent(payload, "", "  ")
 	fmt.Println(string(jsonBytes))
 }
-
-// buildExpectedPayload constructs what we expect the metering payload to look like
-func buildExpectedPayload(m *revenium.UsageMetadata, result *revenium.VideoGenerationResult) map[string]interface{} {
-	payload := map[string]interface{}{
-		// Middleware-populated fields
-		"operationType":            "VIDEO",
-		"provider":                 "runway",
-		"modelSource":              "RUNWAY",
-		"model":                    result.Model,
-		"transactionId":            result.ID,
-		"requestTime":              "(auto)",
-		"responseTime":             "(auto)",
-		"requestDuration":          result.Duration.Milliseconds(),
-		"durationSeconds":          5.0, // Default
-		"requestedDurationSeconds": 10,  // From request
-		"stopReason":               "END",
-		"costType":                 "AI",
-		"isStreamed":               false,
-		"middlewareSource":         revenium.GetMiddlewareSource(),
-
-		// User metadata fields
-		"organizationId":       m.OrganizationID,
-		"productId":            m.ProductID,
-		"taskType":             m.TaskType,
-		"taskId":               m.TaskID,
-		"agent":                m.Agent,
-		"subscriptionId":       m.SubscriptionID,
-		"traceId":              m.TraceID,
-		"parentTransactionId":  m.ParentTransactionID,
-		"traceType":            m.TraceType,
-		"traceName":            m.TraceName,
-		"environment":          m.Environment,
-		"region":               m.Region,
-		"credentialAlias":      m.CredentialAlias,
-		"retryNumber":          *m.RetryNumber,
-		"responseQualityScore": *m.ResponseQualityScore,
-		"videoJobId":           m.VideoJobID,
-		"audioJobId":           m.AudioJobID,
-		"subscriber":           m.Subscriber,
-	}
-
-	// Custom fields are merged at top level
-	for k, v := range m.Custom {
-		payload[k] = v
-	}
-
-	return payload
-}
@@ -0,0 +1,144 @@
+package revenium
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMeteringCircuitThreshold is the number of consecutive metering
+// failures that opens the circuit
+const DefaultMeteringCircuitThreshold = 5
+
+// DefaultMeteringCircuitCooldown is how long the circuit stays open before a
+// probe record is allowed through
+const DefaultMeteringCircuitCooldown = 30 * time.Second
+
+// CircuitState is the state of the metering circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed sends metering normally
+	CircuitClosed CircuitState = "CLOSED"
+	// CircuitOpen fast-fails metering until the cooldown has elapsed
+	CircuitOpen CircuitState = "OPEN"
+	// CircuitHalfOpen lets a single probe record through to test recovery
+	CircuitHalfOpen CircuitState = "HALF_OPEN"
+)
+
+// circuitBreaker stops metering sends after repeated failures so an outage
+// at Revenium is not multiplied by retries from every operation
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int // Consecutive failures that open the circuit; <= 0 disables the breaker
+	cooldown  time.Duration
+	clock     Clock
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probing   bool // A half-open probe is in flight
+}
+
+// newCircuitBreaker creates a breaker from the configured thresholds
+func newCircuitBreaker(cfg *Config) *circuitBreaker {
+	threshold := cfg.MeteringCircuitThreshold
+	if threshold == 0 {
+		threshold = DefaultMeteringCircuitThreshold
+	}
+	cooldown := cfg.MeteringCircuitCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultMeteringCircuitCooldown
+	}
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     cfg.clock(),
+		state:     CircuitClosed,
+	}
+}
+
+// allow reports whether a record may be sent. Once the cooldown has elapsed an
+// open circuit moves to half-open and admits exactly one probe.
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.clock.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		Info("Metering circuit half-open; probing Revenium")
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a send
+func (b *circuitBreaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		if b.state != CircuitClosed {
+			Info("Metering circuit closed; Revenium is reachable again")
+		}
+		b.state = CircuitClosed
+		b.failures = 0
+		b.probing = false
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		if b.state != CircuitOpen {
+			Warn("Metering circuit open after %d consecutive failures; skipping metering for %v", b.failures, b.cooldown)
+		}
+		b.state = CircuitOpen
+		b.openedAt = b.clock.Now()
+		b.probing = false
+	}
+}
+
+// release abandons an admitted send without an outcome (e.g. the context was
+// canceled), so a half-open breaker can admit another probe
+func (b *circuitBreaker) release() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// currentState returns the breaker state, reporting an open circuit whose
+// cooldown has elapsed as half-open
+func (b *circuitBreaker) currentState() CircuitState {
+	if b.threshold <= 0 {
+		return CircuitClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && b.clock.Since(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,117 @@
+package revenium
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := newFakeClock()
+	cfg := testConfig()
+	cfg.MeteringCircuitThreshold = 2
+	cfg.MeteringCircuitCooldown = time.Minute
+	cfg.Clock = clock
+	b := newCircuitBreaker(cfg)
+
+	// Opens after threshold consecutive failures
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("closed breaker rejected send %d", i+1)
+		}
+		b.record(false)
+	}
+	if got := b.currentState(); got != CircuitOpen {
+		t.Fatalf("state after %d failures = %s, want %s", 2, got, CircuitOpen)
+	}
+	if b.allow() {
+		t.Error("open breaker admitted a send during the cooldown")
+	}
+
+	// Half-opens after the cooldown and admits a single probe
+	clock.Advance(time.Minute)
+	if got := b.currentState(); got != CircuitHalfOpen {
+		t.Errorf("state after the cooldown = %s, want %s", got, CircuitHalfOpen)
+	}
+	if !b.allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	if b.allow() {
+		t.Error("half-open breaker admitted a second probe")
+	}
+
+	// A failed probe reopens the circuit
+	b.record(false)
+	if got := b.currentState(); got != CircuitOpen {
+		t.Fatalf("state after a failed probe = %s, want %s", got, CircuitOpen)
+	}
+
+	// An abandoned probe lets another through
+	clock.Advance(time.Minute)
+	if !b.allow() {
+		t.Fatal("half-open breaker rejected the probe")
+	}
+	b.release()
+	if !b.allow() {
+		t.Fatal("half-open breaker rejected a probe after the previous one was released")
+	}
+
+	// A successful probe closes the circuit
+	b.record(true)
+	if got := b.currentState(); got != CircuitClosed {
+		t.Errorf("state after a successful probe = %s, want %s", got, CircuitClosed)
+	}
+	if !b.allow() {
+		t.Error("closed breaker rejected a send")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	cfg := testConfig()
+	cfg.MeteringCircuitThreshold = -1
+	b := newCircuitBreaker(cfg)
+	for i := 0; i < 10; i++ {
+		b.record(false)
+	}
+	if !b.allow() || b.currentState() != CircuitClosed {
+		t.Error("disabled breaker opened")
+	}
+}
+
+func TestMeteringFastFailsWhileCircuitOpen(t *testing.T) {
+	server, requests := statusServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	clock := newFakeClock()
+	cfg := testConfig()
+	cfg.ReveniumBaseURL = server.URL
+	cfg.MeteringCircuitThreshold = 1
+	cfg.MeteringCircuitCooldown = time.Minute
+	cfg.Clock = clock
+	m := NewMeteringClient(cfg)
+	result := &VideoGenerationResult{ID: "task-1", Status: TaskStatusSucceeded}
+	ctx := context.Background()
+
+	if err := m.SendVideoMetering(ctx, result, nil); err == nil {
+		t.Fatal("SendVideoMetering() against a failing endpoint returned no error")
+	}
+	sent := requests.Load()
+	if got := m.CircuitState(); got != CircuitOpen {
+		t.Fatalf("CircuitState() = %s, want %s", got, CircuitOpen)
+	}
+
+	if err := m.SendVideoMetering(ctx, result, nil); !IsMeteringError(err) {
+		t.Errorf("SendVideoMetering() with the circuit open error = %v, want a MeteringError", err)
+	}
+	if got := requests.Load(); got != sent {
+		t.Errorf("requests with the circuit open = %d, want %d", got, sent)
+	}
+
+	// The endpoint has recovered by the end of the cooldown
+	clock.Advance(time.Minute)
+	if err := m.SendVideoMetering(ctx, result, nil); err != nil {
+		t.Fatalf("probe SendVideoMetering() error = %v", err)
+	}
+	if got := m.CircuitState(); got != CircuitClosed {
+		t.Errorf("CircuitState() after a successful probe = %s, want %s", got, CircuitClosed)
+	}
+}
@@ -6,10 +6,77 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// transferCounter accumulates Runway request/response body sizes for one operation
+type transferCounter struct {
+	requestBytes  int64
+	responseBytes int64
+}
+
+// transferCounterKey is the context key for the operation's transferCounter
+type transferCounterKey struct{}
+
+// withTransferCounter attaches a new transferCounter to the context so every
+// Runway call made with it (task creation and polling) is counted
+func withTransferCounter(ctx context.Context) (context.Context, *transferCounter) {
+	counter := &transferCounter{}
+	return context.WithValue(ctx, transferCounterKey{}, counter), counter
+}
+
+// correlationIDKey is the context key for the operation's correlation ID
+type correlationIDKey struct{}
+
+// withCorrelationID attaches a correlation ID to the context so it is sent with
+// every Runway call and prefixed to log lines made with it
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationIDFrom returns the correlation ID attached to the context, if any
+func correlationIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// traceIDKey is the context key for the trace ID sent to Runway
+type traceIDKey struct{}
+
+// ContextWithTraceID attaches a trace ID, such as the active span's trace ID,
+// to the context. Runway requests made with it, including every status poll,
+// carry the ID in the trace header (see WithTraceHeaderName). For generation
+// calls UsageMetadata.TraceID takes precedence.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// traceIDFrom returns the trace ID attached to the context, if any
+func traceIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// logPrefix returns a log line prefix carrying the correlation ID, or "" when unset
+func logPrefix(correlationID string) string {
+	if correlationID == "" {
+		return ""
+	}
+	return "[correlationId=" + correlationID + "] "
+}
+
 // RunwayClient is the HTTP client for interacting with Runway API
 type RunwayClient struct {
 	config     *Config
@@ -23,30 +90,126 @@ func NewRunwayClient(config *Config) *RunwayClient {
 		timeout = DefaultRequestTimeout
 	}
 
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+	if transport := newRunwayTransport(config); transport != nil {
+		httpClient.Transport = transport
+	}
+
 	return &RunwayClient{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
+		config:     config,
+		httpClient: httpClient,
 	}
 }
 
+// newRunwayTransport builds a transport with the configured granular timeouts,
+// or returns nil to use the default transport when none are set
+func newRunwayTransport(config *Config) *http.Transport {
+	if config.RunwayDialTimeout <= 0 && config.RunwayTLSHandshakeTimeout <= 0 && config.RunwayResponseHeaderTimeout <= 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.RunwayDialTimeout > 0 {
+		dialer := &net.Dialer{
+			Timeout:   config.RunwayDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}
+		transport.DialContext = dialer.DialContext
+	}
+	if config.RunwayTLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.RunwayTLSHandshakeTimeout
+	}
+	if config.RunwayResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.RunwayResponseHeaderTimeout
+	}
+	return transport
+}
+
 // CreateImageToVideo creates an image-to-video generation task
 func (c *RunwayClient) CreateImageToVideo(ctx context.Context, req *ImageToVideoRequest) (*TaskResponse, error) {
+	if err := req.validateKeyframes(); err != nil {
+		return nil, err
+	}
+	if err := c.checkImageSizes(ctx, req); err != nil {
+		return nil, err
+	}
+
 	endpoint := "/v1/image_to_video"
-	return c.createTask(ctx, endpoint, req)
+	return c.createTask(ctx, endpoint, req, &req.ClientToken)
+}
+
+// checkImageSizes rejects image inputs over the size limit before they are
+// sent: data URIs by their encoded length and, with WithImagePreflight, URLs by
+// the Content-Length of a HEAD request. Failed preflight requests are ignored.
+func (c *RunwayClient) checkImageSizes(ctx context.Context, req *ImageToVideoRequest) error {
+	uris := []string{req.PromptImage}
+	if len(req.PromptImages) > 0 {
+		uris = uris[:0]
+		for _, img := range req.PromptImages {
+			uris = append(uris, img.URI)
+		}
+	}
+
+	for _, uri := range uris {
+		if strings.HasPrefix(uri, "data:") {
+			limit := c.config.maxImageBytes(DefaultMaxImageDataURIBytes)
+			if size := int64(len(uri)); size > limit {
+				return imageSizeError(size, limit)
+			}
+			continue
+		}
+
+		if !c.config.ImagePreflight || !(strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")) {
+			continue
+		}
+		size, err := headContentLength(ctx, uri)
+		if err != nil {
+			Debug("Image preflight failed, sending anyway: %v", err)
+			continue
+		}
+		if limit := c.config.maxImageBytes(DefaultMaxImageURLBytes); size > limit {
+			return imageSizeError(size, limit)
+		}
+	}
+	return nil
+}
+
+// headContentLength returns the Content-Length reported for url by a HEAD
+// request, or -1 when the server does not report it
+func headContentLength(ctx context.Context, url string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := probeHTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HEAD returned status %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
 }
 
 // CreateVideoToVideo creates a video-to-video generation task
 func (c *RunwayClient) CreateVideoToVideo(ctx context.Context, req *VideoToVideoRequest) (*TaskResponse, error) {
 	endpoint := "/v1/video_to_video"
-	return c.createTask(ctx, endpoint, req)
+	return c.createTask(ctx, endpoint, req, &req.ClientToken)
+}
+
+// CreateTextToVideo creates a text-to-video generation task
+func (c *RunwayClient) CreateTextToVideo(ctx context.Context, req *TextToVideoRequest) (*TaskResponse, error) {
+	endpoint := "/v1/text_to_video"
+	return c.createTask(ctx, endpoint, req, &req.ClientToken)
 }
 
 // CreateVideoUpscale creates a video upscaling task
 func (c *RunwayClient) CreateVideoUpscale(ctx context.Context, req *VideoUpscaleRequest) (*TaskResponse, error) {
 	endpoint := "/v1/video_upscale"
-	return c.createTask(ctx, endpoint, req)
+	return c.createTask(ctx, endpoint, req, &req.ClientToken)
 }
 
 // GetTaskStatus retrieves the status of a task
@@ -58,21 +221,118 @@ func (c *RunwayClient) GetTaskStatus(ctx context.Context, taskID string) (*TaskS
 		return nil, err
 	}
 
-	var response TaskStatusResponse
-	if err := c.doRequest(req, &response); err != nil {
+	var raw json.RawMessage
+	if err := c.doRequest(req, &raw); err != nil {
 		return nil, err
 	}
 
+	var response TaskStatusResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, NewProviderError("failed to decode response", err)
+	}
+	response.Raw = raw
+
 	return &response, nil
 }
 
+// CancelTask cancels a running task, or deletes a finished one, via
+// DELETE /v1/tasks/{id}. Cancellation is best-effort: a task that completes
+// before the request arrives has already been billed by Runway.
+func (c *RunwayClient) CancelTask(ctx context.Context, taskID string) error {
+	endpoint := fmt.Sprintf("/v1/tasks/%s", taskID)
+
+	req, err := c.newRequest(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.doRequest(req, nil)
+}
+
+// ListModels fetches the models available to this API key from Runway,
+// following every page. Callers that need a result even when the endpoint is
+// unavailable should use ReveniumRunway.SupportedModels, which falls back to
+// the static registry.
+func (c *RunwayClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	return c.Models().All(ctx)
+}
+
+// Models returns an iterator over the models available to this API key,
+// fetching pages from Runway as it advances
+func (c *RunwayClient) Models() *Iterator[ModelInfo] {
+	return NewIterator(c.listModelsPage)
+}
+
+// listModelsPage fetches one page of GET /v1/models. A response without a
+// nextCursor is the last page.
+func (c *RunwayClient) listModelsPage(ctx context.Context, cursor string) ([]ModelInfo, string, error) {
+	endpoint := "/v1/models"
+	if cursor != "" {
+		endpoint += "?cursor=" + url.QueryEscape(cursor)
+	}
+
+	req, err := c.newRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var response struct {
+		Models     []ModelInfo `json:"models"`
+		NextCursor string      `json:"nextCursor,omitempty"`
+	}
+	if err := c.doRequest(req, &response); err != nil {
+		return nil, "", err
+	}
+
+	return response.Models, response.NextCursor, nil
+}
+
+// VerifyCredentials confirms the Runway API key is accepted without creating a task.
+// It calls the organization endpoint, which is authenticated but consumes no credits.
+func (c *RunwayClient) VerifyCredentials(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "GET", "/v1/organization", nil)
+	if err != nil {
+		return err
+	}
+
+	return c.doRequest(req, nil)
+}
+
+// runProgressCallback invokes the ProgressCallback, recovering from panics so
+// a faulty callback cannot abort polling
+func runProgressCallback(callback ProgressCallback, taskID string, progress float64) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			Error("ProgressCallback panic for task %s: %v", taskID, rec)
+		}
+	}()
+	callback(taskID, progress)
+}
+
+// pollStats records how a polling loop progressed, used for lifecycle logging
+type pollStats struct {
+	Polls          int       // Number of status requests issued
+	FirstRunningAt time.Time // When the task was first observed RUNNING (zero if never seen)
+	CompletedAt    time.Time // When a terminal status was observed
+	LastProgress   float64   // Progress reported by the last successful poll
+}
+
 // WaitForTaskCompletion polls a task until it completes or times out
 func (c *RunwayClient) WaitForTaskCompletion(ctx context.Context, taskID string, pollingConfig *PollingConfig) (*TaskStatusResponse, error) {
+	status, _, err := c.waitForTask(ctx, taskID, pollingConfig)
+	return status, err
+}
+
+// waitForTask implements WaitForTaskCompletion and additionally reports polling statistics
+func (c *RunwayClient) waitForTask(ctx context.Context, taskID string, pollingConfig *PollingConfig) (*TaskStatusResponse, *pollStats, error) {
 	if pollingConfig == nil {
 		pollingConfig = DefaultPollingConfig()
 	}
 
-	startTime := time.Now()
+	clock := c.config.clock()
+	prefix := logPrefix(correlationIDFrom(ctx))
+	stats := &pollStats{}
+	startTime := clock.Now()
 	interval := pollingConfig.InitialInterval
 	attempts := 0
 
@@ -80,75 +340,160 @@ func (c *RunwayClient) WaitForTaskCompletion(ctx context.Context, taskID string,
 		attempts++
 
 		// Check timeout
-		if time.Since(startTime) > pollingConfig.Timeout {
-			return nil, NewTaskError(fmt.Sprintf("task polling timeout after %v", pollingConfig.Timeout), nil)
+		if elapsed := clock.Since(startTime); elapsed > pollingConfig.Timeout {
+			return nil, stats, NewTaskError(fmt.Sprintf("task polling timeout after %v (%d attempts)", pollingConfig.Timeout, attempts-1), nil).
+				WithDetails("reason", PollingReasonTimeout).
+				WithDetails("timeout", pollingConfig.Timeout).
+				WithDetails("elapsed", elapsed).
+				WithDetails("attempts", attempts-1)
 		}
 
 		// Check max attempts
 		if attempts > pollingConfig.MaxAttempts {
-			return nil, NewTaskError(fmt.Sprintf("max polling attempts (%d) exceeded", pollingConfig.MaxAttempts), nil)
+			elapsed := clock.Since(startTime)
+			return nil, stats, NewTaskError(fmt.Sprintf("max polling attempts (%d) exceeded after %v", pollingConfig.MaxAttempts, elapsed.Round(time.Second)), nil).
+				WithDetails("reason", PollingReasonMaxAttempts).
+				WithDetails("maxAttempts", pollingConfig.MaxAttempts).
+				WithDetails("attempts", attempts-1).
+				WithDetails("elapsed", elapsed)
 		}
 
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, stats, ctx.Err()
 		default:
 		}
 
 		// Poll task status
+		stats.Polls++
 		status, err := c.GetTaskStatus(ctx, taskID)
 		if err != nil {
-			Warn("Failed to get task status (attempt %d): %v", attempts, err)
-			// Continue polling on transient errors
-			time.Sleep(interval)
+			Warn("%sFailed to get task status (attempt %d): %v", prefix, attempts, err)
+			// Continue polling on transient errors, waiting longer during maintenance
+			wait := interval
+			if retryAfter, ok := RetryAfter(err); ok && retryAfter > wait {
+				wait = retryAfter
+				if remaining := pollingConfig.Timeout - clock.Since(startTime); wait > remaining {
+					wait = remaining
+				}
+			}
+			if err := sleepContext(ctx, clock, wait); err != nil {
+				return nil, stats, err
+			}
 			continue
 		}
 
-		Debug("Task %s status: %s (attempt %d)", taskID, status.Status, attempts)
+		Debug("%sTask %s status: %s (attempt %d)", prefix, taskID, status.Status, attempts)
+		if status.Progress != nil {
+			stats.LastProgress = *status.Progress
+			if callback := c.config.ProgressCallback; callback != nil {
+				runProgressCallback(callback, taskID, *status.Progress)
+			}
+		}
+
+		if status.Status == TaskStatusRunning && stats.FirstRunningAt.IsZero() {
+			stats.FirstRunningAt = clock.Now()
+		}
 
 		// Check if task is complete
 		switch status.Status {
 		case TaskStatusSucceeded:
-			Info("Task %s completed successfully", taskID)
-			return status, nil
+			stats.CompletedAt = clock.Now()
+			Info("%sTask %s completed successfully", prefix, taskID)
+			return status, stats, nil
 		case TaskStatusFailed:
+			stats.CompletedAt = clock.Now()
 			errorMsg := "unknown error"
 			if status.Error != nil {
 				errorMsg = *status.Error
 			}
-			return status, NewTaskError(fmt.Sprintf("task failed: %s", errorMsg), nil)
+			taskErr := NewTaskError(fmt.Sprintf("task failed: %s", errorMsg), nil)
+			if pollingConfig.OnFailure != nil && pollingConfig.OnFailure(status) {
+				taskErr.WithDetails("reason", PollingReasonRetryableFailure)
+			}
+			return status, stats, taskErr
 		case TaskStatusCanceled:
-			return status, NewTaskError("task was canceled", nil)
+			stats.CompletedAt = clock.Now()
+			return status, stats, NewTaskError("task was canceled", nil)
 		}
 
 		// Task is still pending or running, wait before next poll
-		time.Sleep(interval)
+		if err := sleepContext(ctx, clock, interval); err != nil {
+			return nil, stats, err
+		}
 
 		// Increase interval with exponential backoff (up to max)
-		interval = time.Duration(float64(interval) * 1.5)
-		if interval > pollingConfig.MaxInterval {
-			interval = pollingConfig.MaxInterval
+		if !pollingConfig.FixedInterval {
+			interval = time.Duration(float64(interval) * 1.5)
+			if interval > pollingConfig.MaxInterval {
+				interval = pollingConfig.MaxInterval
+			}
 		}
 	}
 }
 
-// createTask is a helper to create a task via POST request
-func (c *RunwayClient) createTask(ctx context.Context, endpoint string, reqBody interface{}) (*TaskResponse, error) {
+// sleepContext waits for d on the given clock, returning early with the context
+// error if ctx is done. It starts no goroutine, so a canceled sleep leaves
+// nothing behind.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	var elapsed <-chan time.Time
+	if _, ok := clock.(realClock); ok {
+		// Stop the timer on cancellation rather than keeping it until it fires
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		elapsed = timer.C
+	} else {
+		elapsed = clock.After(d)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-elapsed:
+		return nil
+	}
+}
+
+// createTask is a helper to create a task via POST request. The request's
+// client token, derived first when WithRunwayIdempotency is enabled, is sent
+// as the Idempotency-Key header.
+func (c *RunwayClient) createTask(ctx context.Context, endpoint string, reqBody interface{}, clientToken *string) (*TaskResponse, error) {
+	if *clientToken == "" && c.config.RunwayIdempotency {
+		token, err := idempotencyKey(endpoint, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		*clientToken = token
+	}
+
 	req, err := c.newRequest(ctx, "POST", endpoint, reqBody)
 	if err != nil {
 		return nil, err
 	}
+	if *clientToken != "" {
+		req.Header.Set("Idempotency-Key", *clientToken)
+	}
 
 	var response TaskResponse
 	if err := c.doRequest(req, &response); err != nil {
 		return nil, err
 	}
 
-	Debug("Created task %s with status %s", response.ID, response.Status)
+	Debug("%sCreated task %s with status %s", logPrefix(correlationIDFrom(ctx)), response.ID, response.Status)
 	return &response, nil
 }
 
+// idempotencyKey derives a task creation token from HashRequest over the
+// request body and a scope (the endpoint, or the failed task a resubmission
+// replaces), so a rebuilt request for the same generation gets the same key
+func idempotencyKey(scope string, reqBody interface{}) (string, error) {
+	return HashRequest(struct {
+		Scope   string      `json:"scope"`
+		Request interface{} `json:"request"`
+	}{scope, reqBody})
+}
+
 // newRequest creates a new HTTP request with proper headers
 func (c *RunwayClient) newRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Request, error) {
 	url := c.config.RunwayBaseURL + endpoint
@@ -172,16 +517,25 @@ func (c *RunwayClient) newRequest(ctx context.Context, method, endpoint string,
 	req.Header.Set("X-Runway-Version", c.config.RunwayVersion)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "revenium-middleware-runway-go/1.0")
+	if id := correlationIDFrom(ctx); id != "" {
+		req.Header.Set("X-Correlation-ID", id)
+	}
+	if id := traceIDFrom(ctx); id != "" {
+		req.Header.Set(c.config.traceHeaderName(), id)
+	}
 
 	return req, nil
 }
 
 // doRequest executes an HTTP request and decodes the response
 func (c *RunwayClient) doRequest(req *http.Request, result interface{}) error {
+	start := c.config.clock().Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		logRequest(c.config, "runway", req, 0, start, err)
 		return NewNetworkError("HTTP request failed", err)
 	}
+	logRequest(c.config, "runway", req, resp.StatusCode, start, nil)
 	defer resp.Body.Close()
 
 	// Read response body
@@ -190,8 +544,23 @@ func (c *RunwayClient) doRequest(req *http.Request, result interface{}) error {
 		return NewNetworkError("failed to read response body", err)
 	}
 
+	// Record transfer sizes for byte-count metering
+	if counter, ok := req.Context().Value(transferCounterKey{}).(*transferCounter); ok {
+		if req.ContentLength > 0 {
+			atomic.AddInt64(&counter.requestBytes, req.ContentLength)
+		}
+		atomic.AddInt64(&counter.responseBytes, int64(len(bodyBytes)))
+	}
+
 	// Check for HTTP errors
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Maintenance windows are reported distinctly so callers can pause submissions
+		if isMaintenanceResponse(resp.StatusCode, bodyBytes) {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			Warn("%sRunway API is under maintenance (retry after: %v)", logPrefix(correlationIDFrom(req.Context())), retryAfter)
+			return NewMaintenanceError("Runway API is under maintenance", retryAfter)
+		}
+
 		// Try to parse error response
 		var runwayError RunwayErrorResponse
 		if json.Unmarshal(bodyBytes, &runwayError) == nil && runwayError.Error.Message != "" {
@@ -218,6 +587,33 @@ func (c *RunwayClient) doRequest(req *http.Request, result interface{}) error {
 	return nil
 }
 
+// isMaintenanceResponse reports whether a response is a Runway maintenance notice:
+// a 503 whose body mentions maintenance
+func isMaintenanceResponse(statusCode int, body []byte) bool {
+	if statusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(body)), "maintenance")
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+// It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 // Close closes the HTTP client
 func (c *RunwayClient) Close() error {
 	// Nothing to clean up for HTTP client
@@ -0,0 +1,38 @@
+package revenium
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// stalledClock is a Clock whose After channel never fires
+type stalledClock struct{}
+
+func (stalledClock) Now() time.Time                         { return time.Unix(0, 0) }
+func (stalledClock) After(d time.Duration) <-chan time.Time { return make(chan time.Time) }
+func (stalledClock) Since(t time.Time) time.Duration        { return 0 }
+
+func TestSleepContextCanceledLeavesNoGoroutine(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := sleepContext(ctx, stalledClock{}, time.Hour); !errors.Is(err, context.Canceled) {
+			t.Fatalf("sleepContext() error = %v, want context.Canceled", err)
+		}
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines grew from %d to %d after canceled sleeps", before, after)
+	}
+}
+
+func TestSleepContextElapses(t *testing.T) {
+	if err := sleepContext(context.Background(), realClock{}, time.Millisecond); err != nil {
+		t.Fatalf("sleepContext() error = %v, want nil", err)
+	}
+}
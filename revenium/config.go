@@ -1,8 +1,12 @@
 package revenium
 
 import (
+	"context"
+	"fmt"
+	"mime"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +18,37 @@ import (
 // Video generation can take several minutes, so we use a generous timeout
 const DefaultRequestTimeout = 1800 * time.Second
 
+// DefaultMeteringTimeout is the default timeout for each metering request attempt
+const DefaultMeteringTimeout = 10 * time.Second
+
+// DefaultMeteringContentType is the Content-Type of metering requests
+const DefaultMeteringContentType = "application/json; charset=utf-8"
+
+// DefaultTraceHeaderName is the header that carries the trace ID on Runway requests
+const DefaultTraceHeaderName = "X-Trace-ID"
+
+// DefaultCloseTimeout bounds how long Close waits for pending metering
+const DefaultCloseTimeout = 30 * time.Second
+
+// DefaultMeteringMaxBodyLogBytes bounds the metering body in debug logs
+const DefaultMeteringMaxBodyLogBytes = 2048
+
+// Default provider and modelSource values emitted in metering payloads
+const (
+	DefaultProvider    = "runway"
+	DefaultModelSource = "RUNWAY"
+)
+
+// DefaultModelCacheTTL is how long the model list from Runway is cached
+const DefaultModelCacheTTL = 1 * time.Hour
+
+// ReveniumRegions maps the regions accepted by WithReveniumRegion to their
+// metering base URLs
+var ReveniumRegions = map[string]string{
+	"us": "https://api.revenium.ai",
+	"eu": "https://api.eu.revenium.ai",
+}
+
 // Config holds all configuration for the Revenium middleware
 type Config struct {
 	// Runway API configuration
@@ -22,23 +57,229 @@ type Config struct {
 	RunwayVersion  string
 	RequestTimeout time.Duration
 
+	// Granular Runway transport timeouts (0 uses Go's defaults)
+	RunwayDialTimeout           time.Duration
+	RunwayTLSHandshakeTimeout   time.Duration
+	RunwayResponseHeaderTimeout time.Duration
+
+	// API keys mounted as files (e.g. Kubernetes secrets); read at initialization
+	RunwayAPIKeyFile          string
+	ReveniumAPIKeyFile        string
+	APIKeyFileRefreshInterval time.Duration // When > 0, key files are re-read at this interval for rotation
+
 	// Revenium metering configuration
 	ReveniumAPIKey    string
 	ReveniumBaseURL   string
+	ReveniumRegion    string // Selects the base URL from ReveniumRegions when ReveniumBaseURL is empty
+	AppVersion        string // Host application build/release version, emitted as appVersion
 	ReveniumOrgID     string
 	ReveniumProductID string
+	Provider          string // Emitted as provider (default: DefaultProvider)
+	ModelSource       string // Emitted as modelSource (default: DefaultModelSource)
+
+	// DefaultMetadata is merged under the metadata of every call: its fields
+	// fill in those the call leaves empty (see WithDefaultMetadata)
+	DefaultMetadata *UsageMetadata
+
+	// Billing configuration
+	BillingBasisByModel map[string]BillingBasis // Per-model billing basis overrides (default: per second)
+	PricingTable        map[string]ModelPricing // Per-model prices used to emit cost estimates (optional)
+
+	// Metering delivery configuration
+	MeteringRequired    bool            // When true, metering is sent synchronously and failures are returned to the caller
+	MeteringTimeout     time.Duration   // Timeout per metering request attempt (default: DefaultMeteringTimeout)
+	MeteringContentType string          // Content-Type of metering requests (default: DefaultMeteringContentType)
+	RetryClassifier     RetryClassifier // Custom metering retry policy; DefaultRetryClassifier decides when it returns DeferRetry
+	NoMeteringJitter    bool            // When true, metering retry backoff is not randomized
+	EmitByteCounts      bool            // When true, requestBytes/responseBytes are included in metering payloads
+	MeteringSchedule    time.Duration   // When > 0, metering records are queued and sent at this interval
+	MeteringFlushJitter float64         // Fraction (0 to <1) by which each scheduled send interval is randomized
+	MeterPerOutput      bool            // When true, operations with several outputs emit one record per output
+	InlineMetering      bool            // When true, payloads are built and validated inline but never sent
+	LifecycleMetering   bool            // When true, a start record is sent when the task is created
+
+	// MeteringResponseValidator checks the body of a 2xx metering response
+	// (default: none; see DefaultMeteringResponseValidator)
+	MeteringResponseValidator MeteringResponseValidator
+
+	// StopReasonClassifier overrides the derived stopReason when it returns a non-empty value
+	StopReasonClassifier StopReasonClassifier
+
+	// Metering circuit breaker (defaults: DefaultMeteringCircuitThreshold, DefaultMeteringCircuitCooldown)
+	MeteringCircuitThreshold int           // Consecutive failed records that open the circuit; < 0 disables the breaker
+	MeteringCircuitCooldown  time.Duration // How long the circuit stays open before a probe is sent
+
+	// Output duration probing; when set, the delivered duration is read from the
+	// output video if Runway did not report it
+	OutputDurationProber DurationProber
+
+	// Output download configuration; when OutputDownloadDir is set, outputs of
+	// successful operations are downloaded before the result is returned
+	OutputDownloadDir string
+	MaxDownloadBytes  int64 // Size limit per output (default: DefaultMaxDownloadBytes)
+
+	// Image input size checks
+	MaxImageBytes  int64 // Size limit for image inputs (default: Runway's limits, DefaultMaxImageDataURIBytes / DefaultMaxImageURLBytes)
+	ImagePreflight bool  // When true, image URLs are checked with a HEAD request before submission
+
+	// Payload sanitization configuration
+	SanitizeCustomFields bool // When true, non-JSON-serializable values are stringified or dropped before sending
+	CustomNamespace      bool // When true, Custom fields are sent nested under "custom" instead of at the top level
+
+	// Custom key allowlist (default: nil, every key passes)
+	AllowedCustomKeys []string // When non-nil, Custom keys not listed are dropped from metering payloads
+	StrictMetadata    bool     // When true, operations with unlisted Custom keys are rejected instead
+
+	// Top-level payload keys renamed just before sending (see WithMeteringFieldRenamer)
+	MeteringFieldNames map[string]string
+
+	// Debug log limit for metering bodies (default: DefaultMeteringMaxBodyLogBytes; < 0 = no limit)
+	MeteringMaxBodyLogBytes int
+
+	// Payload size limit (default: DefaultMaxPayloadBytes, PayloadSizeTrim)
+	MaxPayloadBytes   int               // Marshaled payload size limit
+	PayloadSizePolicy PayloadSizePolicy // What to do with a payload over the limit
+
+	// Request validation configuration
+	ModelCapabilityCheck bool             // When true, requests are checked against the model registry before submission
+	RequestValidator     RequestValidator // Caller policy check run before task creation (optional)
+
+	// Duplicate submission guard; when > 0, a traceId seen within this window is rejected
+	TraceDedupWindow time.Duration
+
+	// When true, task creation requests without a ClientToken get one derived from the request
+	RunwayIdempotency bool
+
+	// Operation timeout applied when the caller's context has no deadline (0 = none)
+	DefaultOperationTimeout time.Duration
+
+	// How long Close waits for pending metering (default: DefaultCloseTimeout; < 0 = no bound)
+	CloseTimeout time.Duration
+
+	// Lifecycle hooks
+	OnTaskCreated    TaskCreatedHook  // Called synchronously right after a Runway task is created
+	MeteringCallback MeteringCallback // Called with the outcome of every metering record send
+	ProgressCallback ProgressCallback // Called with the progress reported by each task status poll
+	OnPanic          PanicHook        // Called when an operation method recovers from a panic
+
+	// Header carrying the operation's trace ID on Runway requests (default: DefaultTraceHeaderName)
+	TraceHeaderName string
+
+	// Correlation ID generator; when set, each operation gets an ID that is sent to
+	// Runway, emitted in the metering payload and included in log lines
+	CorrelationIDGenerator func() string
+
+	// Model list cache configuration
+	ModelCacheTTL time.Duration // How long SupportedModels caches Runway's model list (default: DefaultModelCacheTTL)
+
+	// Time source for timestamps, polling and retry backoff (default: real clock)
+	Clock Clock
+
+	// Task polling configuration
+	PollingConfig      *PollingConfig           // Task polling configuration (default: DefaultPollingConfig())
+	TaskTimeoutByModel map[string]time.Duration // Per-model polling timeouts (default: the polling configuration's Timeout)
+
+	// Task concurrency limits (0 = unlimited)
+	MaxConcurrentTasks int            // Concurrent tasks across models without their own limit
+	ConcurrencyByModel map[string]int // Per-model concurrent task limits; listed models are not counted against MaxConcurrentTasks
 
 	// Prompt capture configuration (opt-in for analytics)
 	CapturePrompts bool // When true, captures generation prompts for analytics (default: false)
+	PromptMetrics  bool // When true, emits prompt size metrics (promptChars, promptTokens) without the text
 
 	// Logging and debug configuration
-	LogLevel       string
-	VerboseStartup bool
+	LogLevel           string
+	VerboseStartup     bool
+	LogTimestampFormat string // Time layout for DefaultLogger timestamps (default: DefaultTimestampFormat); applied by Initialize
+	LogUTC             bool   // When true, DefaultLogger timestamps are in UTC; applied by Initialize
+	RequestLogging     bool   // When true, each Runway and Revenium HTTP call is logged at Info
 }
 
+// Clock abstracts the time functions used by the middleware so time-dependent
+// behavior (metering timestamps, polling, retry backoff) can be made deterministic.
+// After must deliver on the returned channel once d has elapsed on the clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Since(t time.Time) time.Duration
+}
+
+// realClock is the default Clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+
 // Option is a functional option for configuring Config
 type Option func(*Config)
 
+// CallOption is a functional option for a single generation call
+type CallOption func(*callOptions)
+
+// callOptions holds the per-call settings applied by CallOption
+type callOptions struct {
+	deadline time.Duration
+	polling  *PollingConfig
+}
+
+// newCallOptions applies opts to a fresh callOptions
+func newCallOptions(opts []CallOption) *callOptions {
+	call := &callOptions{}
+	for _, opt := range opts {
+		opt(call)
+	}
+	return call
+}
+
+// WithOperationDeadline bounds the whole operation - task creation, polling and,
+// when metering is required, the synchronous metering send - by a single deadline.
+// When it is exceeded the call returns a task error whose Details include the
+// "stage" (create, poll or metering) that was running.
+func WithOperationDeadline(d time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.deadline = d
+	}
+}
+
+// WithTaskPolling polls this call's task with pollingConfig instead of the
+// client's polling configuration, e.g. a tighter timeout for short clips so
+// CI fails fast. Per-model timeouts do not apply to it. nil keeps the default.
+func WithTaskPolling(pollingConfig *PollingConfig) CallOption {
+	return func(o *callOptions) {
+		o.polling = pollingConfig
+	}
+}
+
+// WithDefaultOperationTimeout bounds operations whose context has no deadline
+// (e.g. context.Background()) as if WithOperationDeadline(d) had been passed.
+// A deadline on the caller's context, or a per-call WithOperationDeadline,
+// takes precedence.
+func WithDefaultOperationTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DefaultOperationTimeout = d
+	}
+}
+
+// withDeadline derives the operation context from the configured deadline
+func (o *callOptions) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.deadline <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.deadline)
+}
+
+// deadlineError converts err into a stage-annotated task error when the
+// operation deadline has been exceeded
+func (o *callOptions) deadlineError(ctx context.Context, stage string, err error) error {
+	if err == nil || o.deadline <= 0 || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return NewTaskError(fmt.Sprintf("operation deadline of %v exceeded during %s", o.deadline, stage), err).
+		WithDetails("stage", stage).
+		WithDetails("deadline", o.deadline.String())
+}
+
 // WithRunwayAPIKey sets the Runway API key
 func WithRunwayAPIKey(key string) Option {
 	return func(c *Config) {
@@ -60,6 +301,29 @@ func WithReveniumAPIKey(key string) Option {
 	}
 }
 
+// WithRunwayAPIKeyFile reads the Runway API key from a file at initialization
+func WithRunwayAPIKeyFile(path string) Option {
+	return func(c *Config) {
+		c.RunwayAPIKeyFile = path
+	}
+}
+
+// WithReveniumAPIKeyFile reads the Revenium API key from a file at initialization.
+// The key is validated the same way as keys from options or the environment.
+func WithReveniumAPIKeyFile(path string) Option {
+	return func(c *Config) {
+		c.ReveniumAPIKeyFile = path
+	}
+}
+
+// WithAPIKeyFileRefresh re-reads API key files at the given interval so rotated
+// secrets are picked up. Rotated keys that fail validation are ignored.
+func WithAPIKeyFileRefresh(interval time.Duration) Option {
+	return func(c *Config) {
+		c.APIKeyFileRefreshInterval = interval
+	}
+}
+
 // WithReveniumBaseURL sets the Revenium base URL
 func WithReveniumBaseURL(url string) Option {
 	return func(c *Config) {
@@ -67,6 +331,58 @@ func WithReveniumBaseURL(url string) Option {
 	}
 }
 
+// WithAppVersion tags every metering record with the host application's
+// build or release version (appVersion), so usage can be attributed to
+// deployments alongside middlewareSource
+func WithAppVersion(version string) Option {
+	return func(c *Config) {
+		c.AppVersion = version
+	}
+}
+
+// WithProvider sets the provider emitted in metering payloads, for white-label
+// or proxy deployments that front Runway under another name. Revenium routes
+// records to pricing and provider analytics by provider and modelSource, so
+// the values must match what the backend is configured for.
+func WithProvider(provider string) Option {
+	return func(c *Config) {
+		c.Provider = provider
+	}
+}
+
+// WithModelSource sets the modelSource emitted in metering payloads; see WithProvider
+func WithModelSource(source string) Option {
+	return func(c *Config) {
+		c.ModelSource = source
+	}
+}
+
+// provider returns the configured provider or the default
+func (c *Config) provider() string {
+	if c.Provider == "" {
+		return DefaultProvider
+	}
+	return c.Provider
+}
+
+// modelSource returns the configured modelSource or the default
+func (c *Config) modelSource() string {
+	if c.ModelSource == "" {
+		return DefaultModelSource
+	}
+	return c.ModelSource
+}
+
+// WithReveniumRegion selects a known Revenium endpoint by region ("us", "eu").
+// An explicit base URL (WithReveniumBaseURL or REVENIUM_METERING_BASE_URL)
+// takes precedence. Unknown regions fail validation with a ConfigError. The
+// region can also be set with REVENIUM_API_REGION.
+func WithReveniumRegion(region string) Option {
+	return func(c *Config) {
+		c.ReveniumRegion = region
+	}
+}
+
 // WithRequestTimeout sets the HTTP request timeout
 func WithRequestTimeout(timeout time.Duration) Option {
 	return func(c *Config) {
@@ -74,6 +390,18 @@ func WithRequestTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithRunwayTransportTimeouts sets connection-level timeouts for Runway requests:
+// TCP dial, TLS handshake, and waiting for response headers once the request is
+// sent. These fail fast on connectivity problems, while RequestTimeout still
+// bounds each request overall. Zero leaves the corresponding default in place.
+func WithRunwayTransportTimeouts(dial, tlsHandshake, responseHeader time.Duration) Option {
+	return func(c *Config) {
+		c.RunwayDialTimeout = dial
+		c.RunwayTLSHandshakeTimeout = tlsHandshake
+		c.RunwayResponseHeaderTimeout = responseHeader
+	}
+}
+
 // WithCapturePrompts enables/disables prompt capture for analytics
 // When enabled, generation prompts are captured and sent with metering data
 // Default is false (opt-in for privacy)
@@ -83,6 +411,757 @@ func WithCapturePrompts(capture bool) Option {
 	}
 }
 
+// WithBillingBasis sets the billing basis for a specific model
+// Use BillingBasisPerGeneration for models priced per task rather than per second
+func WithBillingBasis(model string, basis BillingBasis) Option {
+	return func(c *Config) {
+		if c.BillingBasisByModel == nil {
+			c.BillingBasisByModel = make(map[string]BillingBasis)
+		}
+		c.BillingBasisByModel[model] = basis
+	}
+}
+
+// ModelPricing is the price of a model, used to estimate cost in metering payloads
+type ModelPricing struct {
+	CreditsPerSecond     float64 // Runway credits per second of output (PER_SECOND models)
+	CreditsPerGeneration float64 // Runway credits per task (PER_GENERATION models)
+	CostPerCredit        float64 // Price of one credit in Currency (0 omits estimatedCost)
+	Currency             string  // ISO 4217 currency code, e.g. "USD"
+}
+
+// WithPricingTable sets per-model prices. For models in the table, metering
+// payloads include estimatedCredits and, when CostPerCredit is set,
+// estimatedCost and currency. These are client-side estimates from the table,
+// not Runway's billed amounts; models not in the table emit no estimate.
+func WithPricingTable(table map[string]ModelPricing) Option {
+	return func(c *Config) {
+		if c.PricingTable == nil {
+			c.PricingTable = make(map[string]ModelPricing, len(table))
+		}
+		for model, pricing := range table {
+			c.PricingTable[model] = pricing
+		}
+	}
+}
+
+// GetBillingBasis returns the billing basis configured for a model
+func (c *Config) GetBillingBasis(model string) BillingBasis {
+	if basis, ok := c.BillingBasisByModel[model]; ok && basis != "" {
+		return basis
+	}
+	return BillingBasisPerSecond
+}
+
+// WithMeteringRequired makes metering part of the operation's success criteria.
+// When enabled, metering is sent synchronously after the task completes. If it
+// still fails after retries, the generation method returns BOTH the result (the
+// video exists and was billed by Runway) and a metering error, so callers must
+// check the result even when err != nil:
+//
+//	result, err := client.ImageToVideo(ctx, req, metadata)
+//	if IsMeteringError(err) && result != nil {
+//		// video was generated but no metering record exists
+//	}
+func WithMeteringRequired(required bool) Option {
+	return func(c *Config) {
+		c.MeteringRequired = required
+	}
+}
+
+// WithMeteringTimeout sets the timeout of each metering request attempt; each
+// of the up to three retried attempts gets the full timeout
+func WithMeteringTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.MeteringTimeout = timeout
+	}
+}
+
+// WithMeteringMaxBodyLogBytes bounds how much of each metering body the debug
+// log shows; longer bodies are cut with a count of the omitted bytes. Only the
+// log line is affected, never what is sent. A negative value logs bodies in full.
+func WithMeteringMaxBodyLogBytes(n int) Option {
+	return func(c *Config) {
+		c.MeteringMaxBodyLogBytes = n
+	}
+}
+
+// WithMeteringContentType overrides the Content-Type of metering requests, for
+// gateways that require a vendor media type such as
+// "application/vnd.revenium.v2+json". The body is JSON either way.
+func WithMeteringContentType(contentType string) Option {
+	return func(c *Config) {
+		c.MeteringContentType = contentType
+	}
+}
+
+// meteringContentType returns the configured metering Content-Type or the default
+func (c *Config) meteringContentType() string {
+	if c.MeteringContentType == "" {
+		return DefaultMeteringContentType
+	}
+	return c.MeteringContentType
+}
+
+// WithMeteringJitter enables or disables jitter on the metering retry backoff
+// (enabled by default). With jitter each wait is drawn from the upper half of
+// the exponential backoff window, so concurrent senders do not retry in
+// lockstep after an outage; disable it for deterministic tests.
+func WithMeteringJitter(enabled bool) Option {
+	return func(c *Config) {
+		c.NoMeteringJitter = !enabled
+	}
+}
+
+// RetryClassifier decides whether a failed metering request should be retried.
+// statusCode is the HTTP status returned by the metering API, or 0 for network
+// errors. A positive backoff overrides the computed exponential backoff.
+// Returning retry false stops retrying, even for failures the default policy
+// retries; return DeferRetry to leave the decision to DefaultRetryClassifier.
+type RetryClassifier func(statusCode int, err error) (retry bool, backoff time.Duration)
+
+// DeferRetry is returned as the backoff by a RetryClassifier that leaves the
+// decision for a failure to DefaultRetryClassifier; retry is then ignored
+const DeferRetry time.Duration = -1
+
+// MeteringResponseValidator inspects the body of a successful metering
+// response and returns an error when the record was not fully accepted
+type MeteringResponseValidator func(body []byte) error
+
+// WithMeteringResponseValidator checks every 2xx metering response body with
+// validator. A validator error fails the record like a 4xx response: it is
+// not retried and is returned as a validation error carrying the status code.
+// Pass DefaultMeteringResponseValidator to reject bodies with an error field.
+func WithMeteringResponseValidator(validator MeteringResponseValidator) Option {
+	return func(c *Config) {
+		c.MeteringResponseValidator = validator
+	}
+}
+
+// WithRetryClassifier sets a custom metering retry policy, consulted before
+// DefaultRetryClassifier. Its decision is final unless it returns DeferRetry,
+// so a classifier can both add retries (e.g. a proxy's transient 422) and stop
+// them (e.g. a proxy's permanent 503), and defer everything else.
+func WithRetryClassifier(classifier RetryClassifier) Option {
+	return func(c *Config) {
+		c.RetryClassifier = classifier
+	}
+}
+
+// WithMeteringSchedule queues metering records and sends them every interval
+// instead of immediately after each operation; Flush sends the queue right away.
+// Payloads are built when the operation finishes, so requestTime/responseTime
+// reflect the operation, not the send. Queued records are held in memory until
+// the next send (roughly 1-2 KB each, more with prompt capture) and are lost if
+// the process exits without Flush or Close. The interval is fixed when the
+// client is created; WithMeteringRequired takes precedence.
+func WithMeteringSchedule(interval time.Duration) Option {
+	return func(c *Config) {
+		c.MeteringSchedule = interval
+	}
+}
+
+// WithMeteringJitteredFlush randomizes each WithMeteringSchedule interval by up
+// to ±fraction (e.g. 0.2 sends every 0.8x to 1.2x the interval), so a fleet of
+// instances started together does not flush in step. Flush and Close still
+// send the queue immediately. The fraction must be at least 0 and below 1.
+func WithMeteringJitteredFlush(fraction float64) Option {
+	return func(c *Config) {
+		c.MeteringFlushJitter = fraction
+	}
+}
+
+// WithMeteringCircuitBreaker configures the metering circuit breaker. After
+// threshold consecutive records fail (after retries), metering fast-fails for
+// cooldown, then a single probe record is sent; success closes the circuit and
+// failure reopens it. Records dropped while the circuit is open are logged.
+// A negative threshold disables the breaker.
+func WithMeteringCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Config) {
+		c.MeteringCircuitThreshold = threshold
+		c.MeteringCircuitCooldown = cooldown
+	}
+}
+
+// StopReasonClassifier maps a finished result to a stopReason. Returning ""
+// keeps the default (END, ERROR or CANCELLED).
+type StopReasonClassifier func(result *VideoGenerationResult) string
+
+// WithStopReasonClassifier sets a classifier consulted for every metering
+// record, so business rules can give certain outcomes (e.g. a safety rejection,
+// identified by FailureCode) a distinct stopReason that backend rules exclude
+// from billed usage
+func WithStopReasonClassifier(classifier StopReasonClassifier) Option {
+	return func(c *Config) {
+		c.StopReasonClassifier = classifier
+	}
+}
+
+// WithInlineMeteringNoNetwork builds and validates metering payloads inline on
+// the calling goroutine but never sends them, so tests can check payload
+// construction without network access or background goroutines (e.g. under
+// goleak). Validation failures are returned to the caller with the result.
+// Unlike WithMeteringRequired nothing is sent, and the payload is only logged
+// at Debug.
+func WithInlineMeteringNoNetwork(enabled bool) Option {
+	return func(c *Config) {
+		c.InlineMetering = enabled
+	}
+}
+
+// WithLifecycleMetering sends a lightweight start record (stopReason
+// IN_PROGRESS, lifecycleEvent START, durationSeconds 0) as soon as the Runway
+// task is created, followed by the usual record on completion (lifecycleEvent
+// COMPLETE). Both records share the task ID as transactionId. The start record
+// is always sent in the background, even with WithMeteringRequired.
+func WithLifecycleMetering(enabled bool) Option {
+	return func(c *Config) {
+		c.LifecycleMetering = enabled
+	}
+}
+
+// WithPerOutputMetering emits one metering record per output when an operation
+// produces several billable artifacts. Each record has its own transactionId
+// ("<taskId>-1", "<taskId>-2", ...) and the task ID as parentTransactionId, so
+// the dashboard can group the outputs under one operation. No separate record
+// is sent for the parent, and a caller-supplied ParentTransactionID is
+// replaced on these records (traceId still links them to the caller's trace).
+// The operation's duration, quantity and cost estimates are split across the
+// records, so their sum bills the operation once.
+func WithPerOutputMetering(enabled bool) Option {
+	return func(c *Config) {
+		c.MeterPerOutput = enabled
+	}
+}
+
+// WithProbeOutputDuration probes the first output video for its duration
+// (using ProbeMP4Duration) when Runway does not report the delivered duration,
+// so metering bills the real length. When probing fails, the requested
+// duration is used.
+func WithProbeOutputDuration(enabled bool) Option {
+	return func(c *Config) {
+		if enabled {
+			c.OutputDurationProber = ProbeMP4Duration
+		} else {
+			c.OutputDurationProber = nil
+		}
+	}
+}
+
+// WithOutputDurationProber enables output duration probing with a custom prober,
+// e.g. one backed by ffprobe
+func WithOutputDurationProber(prober DurationProber) Option {
+	return func(c *Config) {
+		c.OutputDurationProber = prober
+	}
+}
+
+// WithOutputDownloader downloads the outputs of every successful operation into
+// dir before the result is returned, since Runway output URLs expire. Local
+// paths are set in result.LocalPaths; download failures are logged and do not
+// fail the operation. Use DownloadOutputs to download on demand instead.
+func WithOutputDownloader(dir string) Option {
+	return func(c *Config) {
+		c.OutputDownloadDir = dir
+	}
+}
+
+// WithMaxDownloadBytes sets the size limit for each downloaded output
+func WithMaxDownloadBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxDownloadBytes = n
+	}
+}
+
+// WithMaxImageBytes sets the size limit for image inputs. Data URIs are
+// checked by their encoded length before the request is sent, and image URLs
+// too when WithImagePreflight is enabled; oversized images fail with a
+// ValidationError instead of a late Runway rejection or timeout.
+func WithMaxImageBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxImageBytes = n
+	}
+}
+
+// WithImagePreflight checks the Content-Length of image URLs with a HEAD
+// request before submission and rejects images over the size limit
+func WithImagePreflight(enabled bool) Option {
+	return func(c *Config) {
+		c.ImagePreflight = enabled
+	}
+}
+
+// maxImageBytes returns the configured image size limit, or def when unset
+func (c *Config) maxImageBytes(def int64) int64 {
+	if c.MaxImageBytes > 0 {
+		return c.MaxImageBytes
+	}
+	return def
+}
+
+// WithByteCounts includes the Runway request/response body sizes (requestBytes,
+// responseBytes) in metering payloads for network cost accounting. The sizes are
+// always available on VideoGenerationResult regardless of this option.
+func WithByteCounts(emit bool) Option {
+	return func(c *Config) {
+		c.EmitByteCounts = emit
+	}
+}
+
+// WithSanitizeCustomFields stringifies or drops metering values that cannot be
+// marshaled to JSON (for example channels or funcs placed in Custom or
+// Subscriber), logging a warning for each, instead of failing the whole send
+func WithSanitizeCustomFields(sanitize bool) Option {
+	return func(c *Config) {
+		c.SanitizeCustomFields = sanitize
+	}
+}
+
+// DefaultMaxPayloadBytes is the default metering payload size limit, matching
+// the Revenium API's request body limit
+const DefaultMaxPayloadBytes = 1 << 20 // 1 MiB
+
+// PayloadSizePolicy selects how a metering payload over MaxPayloadBytes is handled
+type PayloadSizePolicy string
+
+const (
+	// PayloadSizeTrim drops captured prompts and custom fields, largest first,
+	// until the payload fits, and marks it with payloadTrimmed
+	PayloadSizeTrim PayloadSizePolicy = "TRIM"
+	// PayloadSizeReject fails the send with a ValidationError
+	PayloadSizeReject PayloadSizePolicy = "REJECT"
+)
+
+// WithMaxPayloadBytes sets the marshaled size limit for metering payloads
+// (default DefaultMaxPayloadBytes), so oversized Custom or Subscriber maps or
+// captured prompts are handled before the backend rejects the record with a 413
+func WithMaxPayloadBytes(maxBytes int) Option {
+	return func(c *Config) {
+		c.MaxPayloadBytes = maxBytes
+	}
+}
+
+// WithPayloadSizePolicy sets how payloads over the size limit are handled
+// (default PayloadSizeTrim)
+func WithPayloadSizePolicy(policy PayloadSizePolicy) Option {
+	return func(c *Config) {
+		c.PayloadSizePolicy = policy
+	}
+}
+
+// WithDefaultMetadata sets metadata merged under every call's metadata:
+// OrganizationID, ProductID, TaskType, Agent, SubscriptionID, Environment,
+// Region and CredentialAlias are used when the call leaves them empty, and
+// Custom entries are added unless the call sets the same key. Values from
+// REVENIUM_ORGANIZATION_ID, REVENIUM_PRODUCT_ID, REVENIUM_ENVIRONMENT and
+// REVENIUM_REGION fill fields left empty here.
+func WithDefaultMetadata(metadata *UsageMetadata) Option {
+	return func(c *Config) {
+		c.DefaultMetadata = metadata
+	}
+}
+
+// mergeMetadata returns metadata with empty deployment fields and missing
+// Custom keys filled from defaults. Neither argument is modified.
+func mergeMetadata(defaults, metadata *UsageMetadata) *UsageMetadata {
+	if defaults == nil {
+		return metadata
+	}
+	merged := &UsageMetadata{}
+	if metadata != nil {
+		*merged = *metadata
+	}
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&merged.OrganizationID, defaults.OrganizationID},
+		{&merged.ProductID, defaults.ProductID},
+		{&merged.TaskType, defaults.TaskType},
+		{&merged.Agent, defaults.Agent},
+		{&merged.SubscriptionID, defaults.SubscriptionID},
+		{&merged.Environment, defaults.Environment},
+		{&merged.Region, defaults.Region},
+		{&merged.CredentialAlias, defaults.CredentialAlias},
+	} {
+		if *f.dst == "" {
+			*f.dst = f.src
+		}
+	}
+	if len(defaults.Custom) > 0 {
+		custom := make(map[string]interface{}, len(defaults.Custom)+len(merged.Custom))
+		for k, v := range defaults.Custom {
+			custom[k] = v
+		}
+		for k, v := range merged.Custom {
+			custom[k] = v
+		}
+		merged.Custom = custom
+	}
+	return merged
+}
+
+// WithCustomNamespace sends UsageMetadata.Custom as a nested "custom" object
+// instead of merging its keys into the top level of the payload, keeping them
+// strictly separate from standard and subscriber fields. Reserved keys are
+// allowed inside the namespace.
+func WithCustomNamespace(enabled bool) Option {
+	return func(c *Config) {
+		c.CustomNamespace = enabled
+	}
+}
+
+// WithAllowedCustomKeys restricts UsageMetadata.Custom to the listed keys, to
+// keep unreviewed (possibly personal) data out of metering. Unlisted keys are
+// dropped from the payload with a warning naming them; with WithStrictMetadata
+// the operation is rejected before the task is created instead.
+func WithAllowedCustomKeys(keys []string) Option {
+	return func(c *Config) {
+		c.AllowedCustomKeys = append([]string{}, keys...)
+	}
+}
+
+// WithStrictMetadata makes Custom keys outside WithAllowedCustomKeys a
+// validation error rather than silently dropping them
+func WithStrictMetadata(enabled bool) Option {
+	return func(c *Config) {
+		c.StrictMetadata = enabled
+	}
+}
+
+// filterCustomKeys returns custom without the keys outside AllowedCustomKeys,
+// and the sorted names of the removed keys. Without an allowlist custom is
+// returned unchanged.
+func (c *Config) filterCustomKeys(custom map[string]interface{}) (map[string]interface{}, []string) {
+	if c.AllowedCustomKeys == nil || len(custom) == 0 {
+		return custom, nil
+	}
+
+	allowed := make(map[string]bool, len(c.AllowedCustomKeys))
+	for _, k := range c.AllowedCustomKeys {
+		allowed[k] = true
+	}
+	filtered := make(map[string]interface{}, len(custom))
+	var removed []string
+	for k, v := range custom {
+		if allowed[k] {
+			filtered[k] = v
+		} else {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(removed)
+	return filtered, removed
+}
+
+// checkCustomKeys rejects metadata with Custom keys outside the allowlist when
+// strict metadata is enabled
+func (c *Config) checkCustomKeys(metadata *UsageMetadata) error {
+	if !c.StrictMetadata || metadata == nil {
+		return nil
+	}
+	if _, removed := c.filterCustomKeys(metadata.Custom); len(removed) > 0 {
+		return NewValidationError(fmt.Sprintf("custom keys not in the allowlist: %s", strings.Join(removed, ", ")), nil).
+			WithDetails("keys", removed)
+	}
+	return nil
+}
+
+// WithMeteringFieldRenamer renames top-level metering payload keys just
+// before sending, for Revenium deployments that expect different field names
+// (e.g. {"organizationId": "org_id"}). Renaming applies after the payload is
+// built and validated, so required fields can be renamed but not removed: an
+// empty target name is rejected by Validate.
+func WithMeteringFieldRenamer(names map[string]string) Option {
+	return func(c *Config) {
+		c.MeteringFieldNames = make(map[string]string, len(names))
+		for from, to := range names {
+			c.MeteringFieldNames[from] = to
+		}
+	}
+}
+
+// WithPollingConfig replaces the default task polling configuration for all
+// operations. Per-model timeouts from WithTaskTimeoutPerModel still apply.
+func WithPollingConfig(pollingConfig *PollingConfig) Option {
+	return func(c *Config) {
+		c.PollingConfig = pollingConfig
+	}
+}
+
+// WithPromptMetrics emits the prompt's size as promptChars (Unicode
+// characters) and promptTokens (an estimate at four characters per token),
+// independently of WithCapturePrompts, so size analytics are available
+// without sending the prompt text
+func WithPromptMetrics(enabled bool) Option {
+	return func(c *Config) {
+		c.PromptMetrics = enabled
+	}
+}
+
+// WithTaskTimeoutPerModel sets polling timeouts for specific models.
+// Models not listed use the default polling timeout.
+func WithTaskTimeoutPerModel(timeouts map[string]time.Duration) Option {
+	return func(c *Config) {
+		if c.TaskTimeoutByModel == nil {
+			c.TaskTimeoutByModel = make(map[string]time.Duration, len(timeouts))
+		}
+		for model, timeout := range timeouts {
+			c.TaskTimeoutByModel[model] = timeout
+		}
+	}
+}
+
+// WithMaxConcurrentTasks limits how many Runway tasks run at once (from task
+// creation until polling finishes). Models with a limit from
+// WithPerModelConcurrency are not counted against it.
+func WithMaxConcurrentTasks(n int) Option {
+	return func(c *Config) {
+		c.MaxConcurrentTasks = n
+	}
+}
+
+// WithPerModelConcurrency sets independent concurrent task limits per model,
+// e.g. a low limit for a scarce model and a higher one for a cheap model.
+// Unlisted models fall back to WithMaxConcurrentTasks. Operations waiting for
+// a slot give up when their context is done.
+func WithPerModelConcurrency(limits map[string]int) Option {
+	return func(c *Config) {
+		if c.ConcurrencyByModel == nil {
+			c.ConcurrencyByModel = make(map[string]int, len(limits))
+		}
+		for model, limit := range limits {
+			c.ConcurrencyByModel[model] = limit
+		}
+	}
+}
+
+// pollingConfigFor returns the polling configuration for a model, applying any
+// per-model timeout. MaxAttempts is raised when needed so that a longer timeout
+// is not cut short by the attempt limit.
+func (c *Config) pollingConfigFor(model string) *PollingConfig {
+	pollingConfig := DefaultPollingConfig()
+	if c.PollingConfig != nil {
+		copied := *c.PollingConfig
+		pollingConfig = &copied
+	}
+
+	timeout, ok := c.TaskTimeoutByModel[model]
+	if !ok || timeout <= 0 {
+		Debug("Using default task timeout %v for model %s", pollingConfig.Timeout, model)
+		return pollingConfig
+	}
+
+	pollingConfig.Timeout = timeout
+	if minAttempts := int(timeout/pollingConfig.MaxInterval) + 1; minAttempts > pollingConfig.MaxAttempts {
+		pollingConfig.MaxAttempts = minAttempts
+	}
+
+	Debug("Using per-model task timeout %v for model %s", timeout, model)
+	return pollingConfig
+}
+
+// WithModelCapabilityCheck validates each request with ValidateRequest before
+// creating the Runway task, so unsupported features (e.g. seed or watermark on
+// a model without them) fail fast with a ValidationError instead of a late
+// Runway rejection
+func WithModelCapabilityCheck(enabled bool) Option {
+	return func(c *Config) {
+		c.ModelCapabilityCheck = enabled
+	}
+}
+
+// RequestValidator enforces caller policy on a request and its metadata before
+// the Runway task is created. req is the original request
+// (*ImageToVideoRequest, *VideoToVideoRequest, *TextToVideoRequest or *VideoUpscaleRequest).
+type RequestValidator func(req interface{}, metadata *UsageMetadata) error
+
+// WithRequestValidator registers a validator invoked before every task is
+// created, e.g. to require a subscriptionId in production. A returned error
+// aborts the operation with a ValidationError wrapping it.
+func WithRequestValidator(validator RequestValidator) Option {
+	return func(c *Config) {
+		c.RequestValidator = validator
+	}
+}
+
+// WithTraceDedup rejects, with a DuplicateError, a submission whose
+// UsageMetadata.TraceID was already submitted within window, guarding against
+// retry storms that would bill twice. Submissions that fail before a task is
+// created do not count. The guard is best-effort and in-memory per process; it
+// is not a substitute for server-side idempotency.
+func WithTraceDedup(window time.Duration) Option {
+	return func(c *Config) {
+		c.TraceDedupWindow = window
+	}
+}
+
+// WithRunwayIdempotency derives a ClientToken with HashRequest for task
+// creation requests that have none and sends it as the Idempotency-Key header.
+// Requests with the same content get the same token, so a request rebuilt after
+// a crash or timeout is recognized too; set distinct ClientTokens (or Seeds) to
+// generate identical requests more than once. Automatic resubmissions of a
+// failed task get a token of their own.
+// Runway does not document idempotency keys; if the API ignores the header,
+// retries still create separate tasks.
+func WithRunwayIdempotency(enabled bool) Option {
+	return func(c *Config) {
+		c.RunwayIdempotency = enabled
+	}
+}
+
+// TaskCreatedHook is called with the new task ID and the original request
+// (*ImageToVideoRequest, *VideoToVideoRequest, *TextToVideoRequest or *VideoUpscaleRequest)
+type TaskCreatedHook func(ctx context.Context, taskID string, req interface{}, metadata *UsageMetadata)
+
+// WithOnTaskCreated registers a hook invoked synchronously right after task
+// creation and before polling starts, so callers can persist the task ID to
+// durable storage and recover it if the process dies mid-poll
+func WithOnTaskCreated(hook TaskCreatedHook) Option {
+	return func(c *Config) {
+		c.OnTaskCreated = hook
+	}
+}
+
+// PanicHook is called with the recovered value and stack trace when an
+// operation method recovers from a panic
+type PanicHook func(ctx context.Context, recovered interface{}, stack []byte)
+
+// WithOnPanic registers a hook invoked when an operation method recovers from a
+// panic, e.g. in a callback or hook, so it can be reported to crash tracking.
+// The method itself returns an InternalError carrying the panic value and
+// stack in its details.
+func WithOnPanic(hook PanicHook) Option {
+	return func(c *Config) {
+		c.OnPanic = hook
+	}
+}
+
+// MeteringCallback receives a metering record after its send completes, with
+// the terminal error (nil on success). The payload uses the standard field
+// names and must not be modified.
+type MeteringCallback func(payload map[string]interface{}, err error)
+
+// WithMeteringCallback registers a callback invoked once per metering record
+// after the send and its retries finish, including records sent in the
+// background, so outcomes can feed logging or alerting. It runs on the
+// sending goroutine and should return quickly; panics are recovered.
+func WithMeteringCallback(callback MeteringCallback) Option {
+	return func(c *Config) {
+		c.MeteringCallback = callback
+	}
+}
+
+// WithCloseTimeout bounds how long Close waits for pending metering sends
+// before canceling them. A negative timeout waits without a bound.
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.CloseTimeout = timeout
+	}
+}
+
+// WithTraceHeaderName sets the header that carries the trace ID on Runway
+// requests, for backends that expect a different name (e.g. "X-Request-Trace").
+// The trace ID is UsageMetadata.TraceID, or one attached with ContextWithTraceID.
+func WithTraceHeaderName(name string) Option {
+	return func(c *Config) {
+		c.TraceHeaderName = name
+	}
+}
+
+// traceHeaderName returns the configured trace header name or the default
+func (c *Config) traceHeaderName() string {
+	if c.TraceHeaderName == "" {
+		return DefaultTraceHeaderName
+	}
+	return c.TraceHeaderName
+}
+
+// ProgressCallback receives a task's progress as reported by Runway (0-100)
+type ProgressCallback func(taskID string, progress float64)
+
+// WithProgressCallback registers a callback invoked after each task status poll
+// that reports progress, for every polling loop including
+// WaitForTaskCompletion, so callers can drive a progress bar. It runs on the
+// polling goroutine and delays the next poll while it runs, so it must return
+// quickly (hand the value off to a channel or UI thread); panics are recovered.
+func WithProgressCallback(callback ProgressCallback) Option {
+	return func(c *Config) {
+		c.ProgressCallback = callback
+	}
+}
+
+// WithCorrelationIDGenerator sets a function that generates a correlation ID at
+// the start of every operation. The ID is sent to Runway as the
+// X-Correlation-ID header, emitted in the metering payload as correlationId and
+// prefixed to the operation's log lines.
+func WithCorrelationIDGenerator(generate func() string) Option {
+	return func(c *Config) {
+		c.CorrelationIDGenerator = generate
+	}
+}
+
+// WithModelCacheTTL sets how long SupportedModels caches Runway's model list
+func WithModelCacheTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ModelCacheTTL = ttl
+	}
+}
+
+// WithClock sets the time source used across the Runway and metering clients
+func WithClock(clock Clock) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// clock returns the configured Clock, or the real clock when none is set
+func (c *Config) clock() Clock {
+	if c.Clock == nil {
+		return realClock{}
+	}
+	return c.Clock
+}
+
+// WithLogTimestampFormat sets the time layout of DefaultLogger timestamps,
+// e.g. time.RFC3339 for JSON log tooling. The logger is process-wide, so only
+// Initialize and Reconfigure on the global client apply it; clients from
+// NewReveniumRunway leave the logger alone.
+func WithLogTimestampFormat(layout string) Option {
+	return func(c *Config) {
+		c.LogTimestampFormat = layout
+	}
+}
+
+// WithLogUTC writes DefaultLogger timestamps in UTC instead of local time.
+// Like WithLogTimestampFormat, it is applied only by the global client.
+func WithLogUTC(utc bool) Option {
+	return func(c *Config) {
+		c.LogUTC = utc
+	}
+}
+
+// WithRequestLogging logs a concise access-log line (method, host, path,
+// status, latency) at Info for every Runway and Revenium HTTP call, without
+// bodies, query strings or headers
+func WithRequestLogging(enabled bool) Option {
+	return func(c *Config) {
+		c.RequestLogging = enabled
+	}
+}
+
+// applyLoggerSettings configures the global logger's timestamps when it is a
+// DefaultLogger; custom loggers set via SetLogger are left untouched. Unset
+// fields restore the defaults, so a later configuration can undo an earlier one.
+func (c *Config) applyLoggerSettings() {
+	logger, ok := GetLogger().(*DefaultLogger)
+	if !ok {
+		return
+	}
+	logger.SetTimestampFormat(c.LogTimestampFormat)
+	logger.SetUTC(c.LogUTC)
+}
+
 // LoadFromEnv loads configuration from environment variables and .env files
 func (c *Config) LoadFromEnv() error {
 	// First, try to load .env files automatically
@@ -95,10 +1174,16 @@ func (c *Config) LoadFromEnv() error {
 	c.RequestTimeout = parseDurationFromEnv("RUNWAY_REQUEST_TIMEOUT", DefaultRequestTimeout)
 
 	c.ReveniumAPIKey = os.Getenv("REVENIUM_METERING_API_KEY")
-	baseURL := getEnvOrDefault("REVENIUM_METERING_BASE_URL", "https://api.revenium.ai")
-	c.ReveniumBaseURL = NormalizeReveniumBaseURL(baseURL)
+	if c.ReveniumRegion == "" {
+		c.ReveniumRegion = os.Getenv("REVENIUM_API_REGION")
+	}
+	// Left empty when unset so a region can select the endpoint (see reveniumBaseURL)
+	if baseURL := os.Getenv("REVENIUM_METERING_BASE_URL"); baseURL != "" {
+		c.ReveniumBaseURL = NormalizeReveniumBaseURL(baseURL)
+	}
 	c.ReveniumOrgID = os.Getenv("REVENIUM_ORGANIZATION_ID")
 	c.ReveniumProductID = os.Getenv("REVENIUM_PRODUCT_ID")
+	c.loadDefaultMetadataFromEnv()
 
 	c.LogLevel = getEnvOrDefault("REVENIUM_LOG_LEVEL", "INFO")
 	c.VerboseStartup = os.Getenv("REVENIUM_VERBOSE_STARTUP") == "true" || os.Getenv("REVENIUM_VERBOSE_STARTUP") == "1"
@@ -119,6 +1204,22 @@ func (c *Config) LoadFromEnv() error {
 	return nil
 }
 
+// loadDefaultMetadataFromEnv fills empty DefaultMetadata fields from the
+// environment. REVENIUM_REGION is the deployment region; the Revenium API
+// region is REVENIUM_API_REGION.
+func (c *Config) loadDefaultMetadataFromEnv() {
+	env := &UsageMetadata{
+		OrganizationID: c.ReveniumOrgID,
+		ProductID:      c.ReveniumProductID,
+		Environment:    os.Getenv("REVENIUM_ENVIRONMENT"),
+		Region:         os.Getenv("REVENIUM_REGION"),
+	}
+	if env.OrganizationID == "" && env.ProductID == "" && env.Environment == "" && env.Region == "" {
+		return
+	}
+	c.DefaultMetadata = mergeMetadata(env, c.DefaultMetadata)
+}
+
 // loadEnvFiles loads environment variables from .env files
 func (c *Config) loadEnvFiles() {
 	// Try to load .env files in order of preference
@@ -163,6 +1264,83 @@ func (c *Config) loadEnvFiles() {
 	}
 }
 
+// clone returns a copy of the configuration that shares no mutable state
+func (c *Config) clone() *Config {
+	cp := *c
+	if c.BillingBasisByModel != nil {
+		cp.BillingBasisByModel = make(map[string]BillingBasis, len(c.BillingBasisByModel))
+		for model, basis := range c.BillingBasisByModel {
+			cp.BillingBasisByModel[model] = basis
+		}
+	}
+	if c.PricingTable != nil {
+		cp.PricingTable = make(map[string]ModelPricing, len(c.PricingTable))
+		for model, pricing := range c.PricingTable {
+			cp.PricingTable[model] = pricing
+		}
+	}
+	if c.TaskTimeoutByModel != nil {
+		cp.TaskTimeoutByModel = make(map[string]time.Duration, len(c.TaskTimeoutByModel))
+		for model, timeout := range c.TaskTimeoutByModel {
+			cp.TaskTimeoutByModel[model] = timeout
+		}
+	}
+	if c.DefaultMetadata != nil {
+		// Merging into empty metadata copies the fields that are used, including Custom
+		cp.DefaultMetadata = mergeMetadata(c.DefaultMetadata, nil)
+	}
+	if c.PollingConfig != nil {
+		pollingConfig := *c.PollingConfig
+		cp.PollingConfig = &pollingConfig
+	}
+	if c.MeteringFieldNames != nil {
+		cp.MeteringFieldNames = make(map[string]string, len(c.MeteringFieldNames))
+		for from, to := range c.MeteringFieldNames {
+			cp.MeteringFieldNames[from] = to
+		}
+	}
+	if c.AllowedCustomKeys != nil {
+		cp.AllowedCustomKeys = append([]string{}, c.AllowedCustomKeys...)
+	}
+	if c.ConcurrencyByModel != nil {
+		cp.ConcurrencyByModel = make(map[string]int, len(c.ConcurrencyByModel))
+		for model, limit := range c.ConcurrencyByModel {
+			cp.ConcurrencyByModel[model] = limit
+		}
+	}
+	return &cp
+}
+
+// loadKeyFiles reads API keys from the configured key files, if any
+func (c *Config) loadKeyFiles() error {
+	if c.RunwayAPIKeyFile != "" {
+		key, err := readKeyFile(c.RunwayAPIKeyFile)
+		if err != nil {
+			return NewConfigError("failed to read Runway API key file", err)
+		}
+		c.RunwayAPIKey = key
+	}
+
+	if c.ReveniumAPIKeyFile != "" {
+		key, err := readKeyFile(c.ReveniumAPIKeyFile)
+		if err != nil {
+			return NewConfigError("failed to read Revenium API key file", err)
+		}
+		c.ReveniumAPIKey = key
+	}
+
+	return nil
+}
+
+// readKeyFile reads a key file, trimming trailing newlines
+func readKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.ReveniumAPIKey == "" {
@@ -177,10 +1355,66 @@ func (c *Config) Validate() error {
 		return NewConfigError("RUNWAY_API_KEY is required", nil)
 	}
 
+	if err := c.validateRegion(); err != nil {
+		return err
+	}
+
+	for from, to := range c.MeteringFieldNames {
+		if to == "" {
+			return NewConfigError(fmt.Sprintf("metering field %q cannot be renamed to an empty name", from), nil).
+				WithDetails("field", from)
+		}
+	}
+
+	if c.MeteringFlushJitter < 0 || c.MeteringFlushJitter >= 1 {
+		return NewConfigError(fmt.Sprintf("metering flush jitter must be in [0, 1), got %v", c.MeteringFlushJitter), nil).
+			WithDetails("jitter", c.MeteringFlushJitter)
+	}
+
+	if c.Provider != "" && strings.TrimSpace(c.Provider) == "" {
+		return NewConfigError("provider must not be blank", nil)
+	}
+	if c.ModelSource != "" && strings.TrimSpace(c.ModelSource) == "" {
+		return NewConfigError("model source must not be blank", nil)
+	}
+
+	if c.MeteringContentType != "" {
+		mediaType, _, err := mime.ParseMediaType(c.MeteringContentType)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return NewConfigError(fmt.Sprintf("invalid metering content type %q", c.MeteringContentType), err).
+				WithDetails("contentType", c.MeteringContentType)
+		}
+	}
+
 	Debug("Configuration validation passed")
 	return nil
 }
 
+// validateRegion checks that ReveniumRegion, when set, is a known region
+func (c *Config) validateRegion() error {
+	if c.ReveniumRegion == "" {
+		return nil
+	}
+	if _, ok := ReveniumRegions[strings.ToLower(c.ReveniumRegion)]; !ok {
+		return NewConfigError(fmt.Sprintf("unknown Revenium region %q", c.ReveniumRegion), nil).
+			WithDetails("region", c.ReveniumRegion)
+	}
+	return nil
+}
+
+// reveniumBaseURL returns the effective Revenium base URL: ReveniumBaseURL when
+// set, else the endpoint of ReveniumRegion, else the production endpoint. It is
+// resolved on use so a later region change through Reconfigure takes effect.
+func (c *Config) reveniumBaseURL() string {
+	if c.ReveniumBaseURL != "" {
+		return c.ReveniumBaseURL
+	}
+	if baseURL, ok := ReveniumRegions[strings.ToLower(c.ReveniumRegion)]; ok {
+		return baseURL
+	}
+	return NormalizeReveniumBaseURL("")
+}
+
 // isValidAPIKeyFormat checks if the API key has a valid format
 func isValidAPIKeyFormat(key string) bool {
 	// Revenium API keys should start with "hak_"
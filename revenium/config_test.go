@@ -0,0 +1,94 @@
+package revenium
+
+import "testing"
+
+// testConfig returns a minimal valid configuration that meters to an
+// unreachable endpoint
+func testConfig() *Config {
+	return &Config{
+		RunwayAPIKey:    "key_test",
+		ReveniumAPIKey:  "hak_test",
+		ReveniumBaseURL: "http://127.0.0.1:1",
+	}
+}
+
+func TestValidateDoesNotResolveRegion(t *testing.T) {
+	cfg := &Config{RunwayAPIKey: "key_test", ReveniumAPIKey: "hak_test", ReveniumRegion: "eu"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if cfg.ReveniumBaseURL != "" {
+		t.Errorf("Validate() set ReveniumBaseURL to %q, want it left empty", cfg.ReveniumBaseURL)
+	}
+	if got := cfg.reveniumBaseURL(); got != ReveniumRegions["eu"] {
+		t.Errorf("reveniumBaseURL() = %q, want %q", got, ReveniumRegions["eu"])
+	}
+}
+
+func TestReconfigureChangesRegion(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReveniumBaseURL = ""
+	cfg.ReveniumRegion = "us"
+	r, err := NewReveniumRunway(cfg)
+	if err != nil {
+		t.Fatalf("NewReveniumRunway() error = %v", err)
+	}
+	defer r.Close()
+
+	if err := r.Reconfigure(WithReveniumRegion("eu")); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if got := r.GetConfig().reveniumBaseURL(); got != ReveniumRegions["eu"] {
+		t.Errorf("reveniumBaseURL() after Reconfigure = %q, want %q", got, ReveniumRegions["eu"])
+	}
+}
+
+func TestReveniumBaseURLPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		region  string
+		want    string
+	}{
+		{"explicit base URL wins", "https://metering.example.com", "eu", "https://metering.example.com"},
+		{"region without base URL", "", "EU", ReveniumRegions["eu"]},
+		{"neither set", "", "", "https://api.revenium.ai"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ReveniumBaseURL: tt.baseURL, ReveniumRegion: tt.region}
+			if got := cfg.reveniumBaseURL(); got != tt.want {
+				t.Errorf("reveniumBaseURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsUnknownRegion(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReveniumRegion = "mars"
+	if err := cfg.Validate(); !IsConfigError(err) {
+		t.Errorf("Validate() error = %v, want a ConfigError", err)
+	}
+}
+
+func TestLoadFromEnvRegionIsDeploymentMetadata(t *testing.T) {
+	t.Setenv("RUNWAY_API_KEY", "key_test")
+	t.Setenv("REVENIUM_METERING_API_KEY", "hak_test")
+	t.Setenv("REVENIUM_REGION", "us-east-1")
+	t.Setenv("REVENIUM_API_REGION", "")
+
+	cfg := &Config{}
+	if err := cfg.LoadFromEnv(); err != nil {
+		t.Fatalf("LoadFromEnv() error = %v", err)
+	}
+	if cfg.DefaultMetadata == nil || cfg.DefaultMetadata.Region != "us-east-1" {
+		t.Errorf("DefaultMetadata = %+v, want Region us-east-1", cfg.DefaultMetadata)
+	}
+	if cfg.ReveniumRegion != "" {
+		t.Errorf("ReveniumRegion = %q, want it unset", cfg.ReveniumRegion)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
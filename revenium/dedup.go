@@ -0,0 +1,73 @@
+package revenium
+
+import (
+	"sync"
+	"time"
+)
+
+// traceGuard rejects repeat submissions of a trace ID within a time window.
+// It is best-effort and per-process. A nil traceGuard admits everything.
+type traceGuard struct {
+	mu        sync.Mutex
+	window    time.Duration
+	clock     Clock
+	seen      map[string]time.Time // Trace ID -> submission time
+	lastPrune time.Time
+}
+
+// newTraceGuard creates a guard for the configured window, or returns nil when
+// trace deduplication is disabled
+func newTraceGuard(cfg *Config) *traceGuard {
+	if cfg.TraceDedupWindow <= 0 {
+		return nil
+	}
+	return &traceGuard{
+		window: cfg.TraceDedupWindow,
+		clock:  cfg.clock(),
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// admit records a submission of traceID and reports whether it is the first
+// within the window. Empty trace IDs are always admitted.
+func (g *traceGuard) admit(traceID string) bool {
+	if g == nil || traceID == "" {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.clock.Now()
+	g.prune(now)
+	if at, ok := g.seen[traceID]; ok && now.Sub(at) < g.window {
+		return false
+	}
+	g.seen[traceID] = now
+	return true
+}
+
+// forget removes a trace ID whose submission failed before a task was
+// created, so the caller can retry it
+func (g *traceGuard) forget(traceID string) {
+	if g == nil || traceID == "" {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.seen, traceID)
+}
+
+// prune drops expired entries at most once per window. The caller must hold g.mu.
+func (g *traceGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < g.window {
+		return
+	}
+	for traceID, at := range g.seen {
+		if now.Sub(at) >= g.window {
+			delete(g.seen, traceID)
+		}
+	}
+	g.lastPrune = now
+}
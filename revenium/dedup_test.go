@@ -0,0 +1,70 @@
+package revenium
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTraceGuard(t *testing.T) {
+	clock := newFakeClock()
+	cfg := testConfig()
+	cfg.TraceDedupWindow = time.Minute
+	cfg.Clock = clock
+	g := newTraceGuard(cfg)
+
+	if !g.admit("trace-1") {
+		t.Fatal("first submission of a trace was rejected")
+	}
+	if g.admit("trace-1") {
+		t.Error("repeat submission within the window was admitted")
+	}
+	if !g.admit("trace-2") {
+		t.Error("submission of another trace was rejected")
+	}
+	if !g.admit("") || !g.admit("") {
+		t.Error("submissions without a trace ID were rejected")
+	}
+
+	clock.Advance(time.Minute)
+	if !g.admit("trace-1") {
+		t.Error("submission after the window was rejected")
+	}
+
+	g.forget("trace-2")
+	if !g.admit("trace-2") {
+		t.Error("forgotten trace was rejected")
+	}
+}
+
+func TestTraceGuardDisabled(t *testing.T) {
+	g := newTraceGuard(testConfig())
+	if g != nil {
+		t.Fatal("newTraceGuard() without a window returned a guard")
+	}
+	if !g.admit("trace-1") || !g.admit("trace-1") {
+		t.Error("disabled guard rejected a submission")
+	}
+}
+
+func TestDuplicateTraceRejected(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithTraceDedup(time.Minute))
+	ctx := context.Background()
+	metadata := &UsageMetadata{TraceID: "trace-1"}
+
+	if _, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a lighthouse"}, metadata); err != nil {
+		t.Fatalf("first TextToVideo() error = %v", err)
+	}
+	_, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a lighthouse"}, metadata)
+	if !IsDuplicateError(err) {
+		t.Fatalf("repeat TextToVideo() error = %v, want a DuplicateError", err)
+	}
+	if _, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a lighthouse"}, &UsageMetadata{TraceID: "trace-2"}); err != nil {
+		t.Errorf("TextToVideo() with another trace error = %v", err)
+	}
+	if got := runway.nextID.Load(); got != 2 {
+		t.Errorf("tasks created = %d, want 2", got)
+	}
+}
@@ -0,0 +1,112 @@
+package revenium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// DefaultMaxDownloadBytes is the default size limit for each downloaded output
+const DefaultMaxDownloadBytes = 1 << 30 // 1 GiB
+
+// downloadHTTPClient fetches output videos. There is no overall timeout
+// because large videos take a while; the caller's context bounds the download.
+var downloadHTTPClient = &http.Client{}
+
+// DownloadOutputs streams each of the result's output URLs into dir (created
+// if needed) and records the local paths in result.LocalPaths, index-aligned
+// with OutputURLs. Each file is named after the task ID and output index. An
+// output that fails or exceeds the configured size limit leaves an empty path;
+// the other outputs are still downloaded and the failures are returned joined.
+func (r *ReveniumRunway) DownloadOutputs(ctx context.Context, result *VideoGenerationResult, dir string) error {
+	if len(result.OutputURLs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return NewConfigError("failed to create download directory", err)
+	}
+
+	maxBytes := r.snapshot().config.MaxDownloadBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxDownloadBytes
+	}
+
+	result.LocalPaths = make([]string, len(result.OutputURLs))
+	var errs []error
+	for i, outputURL := range result.OutputURLs {
+		dest := filepath.Join(dir, fmt.Sprintf("%s-%d%s", result.ID, i+1, outputExtension(outputURL)))
+		if err := downloadFile(ctx, outputURL, dest, maxBytes); err != nil {
+			errs = append(errs, fmt.Errorf("output %d: %w", i+1, err))
+			continue
+		}
+		result.LocalPaths[i] = dest
+	}
+
+	if len(errs) > 0 {
+		Warn("%sDownloaded %d of %d outputs for task %s", logPrefix(result.CorrelationID), len(result.OutputURLs)-len(errs), len(result.OutputURLs), result.ID)
+		return errors.Join(errs...)
+	}
+	Debug("Downloaded %d outputs for task %s to %s", len(result.OutputURLs), result.ID, dir)
+	return nil
+}
+
+// outputExtension returns the file extension of an output URL's path, or
+// ".mp4" when it has none
+func outputExtension(outputURL string) string {
+	if u, err := url.Parse(outputURL); err == nil {
+		if ext := path.Ext(u.Path); ext != "" {
+			return ext
+		}
+	}
+	return ".mp4"
+}
+
+// downloadFile streams url to dest through a temporary file, so a failed or
+// oversized download never leaves a partial file at dest
+func downloadFile(ctx context.Context, url, dest string, maxBytes int64) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return NewProviderError("failed to create download request", err)
+	}
+
+	resp, err := downloadHTTPClient.Do(req)
+	if err != nil {
+		return NewNetworkError("download request failed", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return NewProviderError(fmt.Sprintf("download returned status %d", resp.StatusCode), nil)
+	}
+	if resp.ContentLength > maxBytes {
+		return NewValidationError(fmt.Sprintf("output is %d bytes, limit is %d", resp.ContentLength, maxBytes), nil)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return NewInternalError("failed to create download file", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	written, err := io.Copy(tmp, io.LimitReader(resp.Body, maxBytes+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return NewNetworkError("failed to download output", err)
+	}
+	if written > maxBytes {
+		return NewValidationError(fmt.Sprintf("output exceeds the %d byte limit", maxBytes), nil)
+	}
+
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return NewInternalError("failed to save downloaded output", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package revenium
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SubscriberInfo describes the end user making a request
+type SubscriberInfo struct {
+	ID         string
+	Email      string
+	Name       string
+	Role       string
+	Department string
+	Extra      map[string]interface{} // Additional subscriber attributes
+}
+
+// CampaignInfo identifies the campaign a generation belongs to
+type CampaignInfo struct {
+	ID    string
+	Name  string
+	Phase string
+}
+
+// EnterpriseContext is a typed alternative to building UsageMetadata by hand.
+// ToUsageMetadata maps its fields to the standard UsageMetadata fields and to
+// the Subscriber and Custom maps under consistent key names.
+type EnterpriseContext struct {
+	// Standard fields
+	OrganizationID  string
+	ProductID       string
+	SubscriptionID  string
+	TaskType        string
+	Agent           string
+	Environment     string
+	Region          string
+	CredentialAlias string
+
+	Subscriber SubscriberInfo // Emitted as the subscriber object
+	Campaign   CampaignInfo   // Emitted as campaignId, campaignName, campaignPhase
+
+	// Cost attribution, emitted as costCenter, projectCode and budgetCode
+	CostCenter  string
+	ProjectCode string
+	BudgetCode  string
+
+	Custom map[string]interface{} // Additional custom fields; typed fields take precedence
+}
+
+// Validate checks that the context has an organization ID, that custom keys
+// do not collide with payload fields and that the subscriber email looks valid
+func (e EnterpriseContext) Validate() error {
+	if e.OrganizationID == "" {
+		return NewValidationError("enterprise context requires an organization ID", nil)
+	}
+	if e.Subscriber.Email != "" && !strings.Contains(e.Subscriber.Email, "@") {
+		return NewValidationError(fmt.Sprintf("invalid subscriber email %q", e.Subscriber.Email), nil)
+	}
+	for k := range e.Custom {
+		if IsReservedPayloadKey(k) {
+			return NewValidationError(fmt.Sprintf("custom key %q is a reserved metering field", k), nil).
+				WithDetails("key", k)
+		}
+	}
+	return nil
+}
+
+// ToUsageMetadata builds the UsageMetadata for the context. Empty fields are
+// omitted, and the Subscriber and Custom maps are only set when non-empty.
+func (e EnterpriseContext) ToUsageMetadata() *UsageMetadata {
+	metadata := &UsageMetadata{
+		OrganizationID:  e.OrganizationID,
+		ProductID:       e.ProductID,
+		SubscriptionID:  e.SubscriptionID,
+		TaskType:        e.TaskType,
+		Agent:           e.Agent,
+		Environment:     e.Environment,
+		Region:          e.Region,
+		CredentialAlias: e.CredentialAlias,
+	}
+
+	subscriber := make(map[string]interface{}, len(e.Subscriber.Extra)+5)
+	for k, v := range e.Subscriber.Extra {
+		subscriber[k] = v
+	}
+	setNonEmpty(subscriber, map[string]string{
+		"id":         e.Subscriber.ID,
+		"email":      e.Subscriber.Email,
+		"name":       e.Subscriber.Name,
+		"role":       e.Subscriber.Role,
+		"department": e.Subscriber.Department,
+	})
+	if len(subscriber) > 0 {
+		metadata.Subscriber = subscriber
+	}
+
+	custom := make(map[string]interface{}, len(e.Custom)+6)
+	for k, v := range e.Custom {
+		custom[k] = v
+	}
+	setNonEmpty(custom, map[string]string{
+		"campaignId":    e.Campaign.ID,
+		"campaignName":  e.Campaign.Name,
+		"campaignPhase": e.Campaign.Phase,
+		"costCenter":    e.CostCenter,
+		"projectCode":   e.ProjectCode,
+		"budgetCode":    e.BudgetCode,
+	})
+	if len(custom) > 0 {
+		metadata.Custom = custom
+	}
+
+	return metadata
+}
+
+// setNonEmpty copies the non-empty values into m, overwriting existing keys
+func setNonEmpty(m map[string]interface{}, values map[string]string) {
+	for k, v := range values {
+		if v != "" {
+			m[k] = v
+		}
+	}
+}
@@ -3,6 +3,7 @@ package revenium
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // ErrorType represents the type of error that occurred
@@ -30,6 +31,12 @@ const (
 	// Validation errors
 	ErrorTypeValidation ErrorType = "VALIDATION_ERROR"
 
+	// Provider maintenance (Runway intentionally unavailable)
+	ErrorTypeMaintenance ErrorType = "MAINTENANCE_ERROR"
+
+	// Duplicate submissions rejected by the trace ID guard
+	ErrorTypeDuplicate ErrorType = "DUPLICATE_ERROR"
+
 	// Internal errors
 	ErrorTypeInternal ErrorType = "INTERNAL_ERROR"
 )
@@ -79,7 +86,7 @@ func (e *ReveniumError) GetStatusCode() int {
 		return 401
 	case ErrorTypeProvider, ErrorTypeTask:
 		return 502
-	case ErrorTypeNetwork:
+	case ErrorTypeNetwork, ErrorTypeMaintenance:
 		return 503
 	case ErrorTypeMetering:
 		return 500
@@ -168,6 +175,28 @@ func NewValidationError(message string, err error) *ReveniumError {
 	}
 }
 
+// NewMaintenanceError creates a new provider maintenance error.
+// retryAfter is the server-suggested wait, or 0 if none was provided.
+func NewMaintenanceError(message string, retryAfter time.Duration) *ReveniumError {
+	err := &ReveniumError{
+		Type:       ErrorTypeMaintenance,
+		Message:    message,
+		StatusCode: 503,
+	}
+	if retryAfter > 0 {
+		err.WithDetails("retryAfter", retryAfter)
+	}
+	return err
+}
+
+// NewDuplicateError creates an error for a submission rejected by WithTraceDedup
+func NewDuplicateError(message string, traceID string) *ReveniumError {
+	return (&ReveniumError{
+		Type:    ErrorTypeDuplicate,
+		Message: message,
+	}).WithDetails("traceId", traceID)
+}
+
 // NewInternalError creates a new internal error
 func NewInternalError(message string, err error) *ReveniumError {
 	return &ReveniumError{
@@ -219,6 +248,70 @@ func IsValidationError(err error) bool {
 	return errors.As(err, &revErr) && revErr.Type == ErrorTypeValidation
 }
 
+// Polling failure reasons, stored in the "reason" detail of task errors
+const (
+	PollingReasonTimeout     = "timeout"
+	PollingReasonMaxAttempts = "max_attempts"
+	// PollingReasonRetryableFailure marks a FAILED task that PollingConfig.OnFailure
+	// classified as transient
+	PollingReasonRetryableFailure = "retryable_failure"
+)
+
+// IsPollingTimeout checks if an error is a task error caused by the polling timeout
+func IsPollingTimeout(err error) bool {
+	return pollingReason(err) == PollingReasonTimeout
+}
+
+// IsPollingExhausted checks if an error is a task error caused by running out of polling attempts
+func IsPollingExhausted(err error) bool {
+	return pollingReason(err) == PollingReasonMaxAttempts
+}
+
+// IsRetryableTaskFailure checks if an error is a failed task that
+// PollingConfig.OnFailure classified as transient
+func IsRetryableTaskFailure(err error) bool {
+	return pollingReason(err) == PollingReasonRetryableFailure
+}
+
+// pollingReason returns the polling failure reason of a task error, if any
+func pollingReason(err error) string {
+	var revErr *ReveniumError
+	if !errors.As(err, &revErr) || revErr.Type != ErrorTypeTask {
+		return ""
+	}
+	reason, _ := revErr.Details["reason"].(string)
+	return reason
+}
+
+// IsMaintenanceError checks if an error indicates Runway is down for maintenance
+func IsMaintenanceError(err error) bool {
+	var revErr *ReveniumError
+	return errors.As(err, &revErr) && revErr.Type == ErrorTypeMaintenance
+}
+
+// RetryAfter returns the server-suggested wait carried by an error, if any
+func RetryAfter(err error) (time.Duration, bool) {
+	var revErr *ReveniumError
+	if !errors.As(err, &revErr) {
+		return 0, false
+	}
+	d, ok := revErr.Details["retryAfter"].(time.Duration)
+	return d, ok
+}
+
+// IsDuplicateError checks if an error is a duplicate trace ID submission
+func IsDuplicateError(err error) bool {
+	var revErr *ReveniumError
+	return errors.As(err, &revErr) && revErr.Type == ErrorTypeDuplicate
+}
+
+// IsInternalError checks if an error is an internal error, such as a panic
+// recovered in an operation method
+func IsInternalError(err error) bool {
+	var revErr *ReveniumError
+	return errors.As(err, &revErr) && revErr.Type == ErrorTypeInternal
+}
+
 // IsReveniumError checks if an error is a ReveniumError
 func IsReveniumError(err error) bool {
 	var revErr *ReveniumError
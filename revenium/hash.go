@@ -0,0 +1,64 @@
+package revenium
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// volatileHashKeys are fields excluded from HashRequest because they change
+// between otherwise identical submissions (retries, tracing)
+var volatileHashKeys = map[string]bool{
+	"retryNumber":         true,
+	"traceId":             true,
+	"parentTransactionId": true,
+}
+
+// HashRequest returns a stable hex-encoded SHA-256 of a request, suitable for
+// deduplication and logging. The request is normalized through its JSON form:
+// object keys are sorted, empty fields follow the request's omitempty tags, and
+// volatile fields (retry number and trace IDs) are removed at any depth. It
+// accepts any JSON-serializable value, including requests and UsageMetadata.
+func HashRequest(req interface{}) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", NewValidationError("failed to serialize request for hashing", err)
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return "", NewValidationError("failed to normalize request for hashing", err)
+	}
+	normalized = stripVolatile(normalized)
+
+	// encoding/json writes map keys in sorted order, giving a canonical form
+	canonical, err := json.Marshal(normalized)
+	if err != nil {
+		return "", NewValidationError("failed to serialize normalized request", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// stripVolatile removes volatileHashKeys from decoded JSON objects recursively
+func stripVolatile(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if volatileHashKeys[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripVolatile(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stripVolatile(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
@@ -0,0 +1,142 @@
+package revenium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestHashRequest(t *testing.T) {
+	seed := 7
+	base := &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a fox", Duration: 5, Seed: &seed}
+	baseHash, err := HashRequest(base)
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+
+	otherSeed := 8
+	tests := []struct {
+		name     string
+		req      interface{}
+		wantSame bool
+	}{
+		{"same content, new value", &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a fox", Duration: 5, Seed: &seed}, true},
+		{"same fields in another key order", map[string]interface{}{"seed": 7, "duration": 5, "promptText": "a fox", "promptImage": "https://example.com/frame.png"}, true},
+		{"client token is not part of the body", &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a fox", Duration: 5, Seed: &seed, ClientToken: "token"}, true},
+		{"prompt changed", &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a wolf", Duration: 5, Seed: &seed}, false},
+		{"seed changed", &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a fox", Duration: 5, Seed: &otherSeed}, false},
+		{"duration changed", &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", PromptText: "a fox", Duration: 10, Seed: &seed}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashRequest(tt.req)
+			if err != nil {
+				t.Fatalf("HashRequest() error = %v", err)
+			}
+			if (got == baseHash) != tt.wantSame {
+				t.Errorf("HashRequest() equal to base = %v, want %v", got == baseHash, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestHashRequestIgnoresVolatileFields(t *testing.T) {
+	retry := 2
+	base, err := HashRequest(&UsageMetadata{OrganizationID: "org", TaskType: "trailer"})
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		metadata *UsageMetadata
+		wantSame bool
+	}{
+		{"trace ID", &UsageMetadata{OrganizationID: "org", TaskType: "trailer", TraceID: "trace-1"}, true},
+		{"parent transaction ID", &UsageMetadata{OrganizationID: "org", TaskType: "trailer", ParentTransactionID: "parent"}, true},
+		{"retry number", &UsageMetadata{OrganizationID: "org", TaskType: "trailer", RetryNumber: &retry}, true},
+		{"organization changed", &UsageMetadata{OrganizationID: "other", TaskType: "trailer", TraceID: "trace-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashRequest(tt.metadata)
+			if err != nil {
+				t.Fatalf("HashRequest() error = %v", err)
+			}
+			if (got == base) != tt.wantSame {
+				t.Errorf("HashRequest() equal to base = %v, want %v", got == base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestHashRequestStripsNestedVolatileFields(t *testing.T) {
+	a, err := HashRequest(map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "traceId": "a"}}})
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+	b, err := HashRequest(map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": 1, "traceId": "b"}}})
+	if err != nil {
+		t.Fatalf("HashRequest() error = %v", err)
+	}
+	if a != b {
+		t.Error("nested traceId changed the hash")
+	}
+}
+
+func TestHashRequestRejectsUnserializable(t *testing.T) {
+	if _, err := HashRequest(map[string]interface{}{"callback": func() {}}); !IsValidationError(err) {
+		t.Errorf("HashRequest() error = %v, want a ValidationError", err)
+	}
+}
+
+func TestDerivedIdempotencyKeys(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(TaskResponse{ID: "task-1", Status: TaskStatusPending})
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.RunwayBaseURL = server.URL
+	cfg.RunwayIdempotency = true
+	client := NewRunwayClient(cfg)
+	ctx := context.Background()
+
+	create := func(req *TextToVideoRequest) string {
+		t.Helper()
+		if _, err := client.CreateTextToVideo(ctx, req); err != nil {
+			t.Fatalf("CreateTextToVideo() error = %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return keys[len(keys)-1]
+	}
+
+	first := create(&TextToVideoRequest{PromptText: "a storm at sea"})
+	if first == "" {
+		t.Fatal("no Idempotency-Key sent with WithRunwayIdempotency")
+	}
+	if rebuilt := create(&TextToVideoRequest{PromptText: "a storm at sea"}); rebuilt != first {
+		t.Errorf("rebuilt request key = %q, want %q", rebuilt, first)
+	}
+	if other := create(&TextToVideoRequest{PromptText: "a calm sea"}); other == first {
+		t.Error("different requests share an Idempotency-Key")
+	}
+
+	// A resubmission of a failed task must not reuse the failed task's key
+	req := &TextToVideoRequest{PromptText: "a storm at sea"}
+	if err := resubmitClientToken(req, "task-1", true); err != nil {
+		t.Fatalf("resubmitClientToken() error = %v", err)
+	}
+	if resubmitted := create(req); resubmitted == "" || resubmitted == first {
+		t.Errorf("resubmission key = %q, want a new key", resubmitted)
+	}
+}
@@ -0,0 +1,152 @@
+package revenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRunway is a Runway API stub: created tasks succeed on their first status
+// poll with one output URL
+type fakeRunway struct {
+	*httptest.Server
+	nextID atomic.Int64
+	// status returns the status response for a task; nil means SUCCEEDED
+	status func(taskID string) *TaskStatusResponse
+}
+
+func newFakeRunway(t *testing.T) *fakeRunway {
+	t.Helper()
+	f := &fakeRunway{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeRunway) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost:
+		id := fmt.Sprintf("task-%d", f.nextID.Add(1))
+		json.NewEncoder(w).Encode(TaskResponse{ID: id, Status: TaskStatusPending})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/tasks/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v1/tasks/")
+		var status *TaskStatusResponse
+		if f.status != nil {
+			status = f.status(id)
+		}
+		if status == nil {
+			status = &TaskStatusResponse{ID: id, Status: TaskStatusSucceeded, Output: []string{"https://cdn.example.com/" + id + ".mp4"}}
+		}
+		json.NewEncoder(w).Encode(status)
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fakeMetering is a Revenium metering stub that records every payload
+type fakeMetering struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+}
+
+func newFakeMetering(t *testing.T) *fakeMetering {
+	t.Helper()
+	f := &fakeMetering{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.payloads = append(f.payloads, payload)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// received returns a copy of the payloads recorded so far
+func (f *fakeMetering) received() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.payloads...)
+}
+
+// fastPolling polls every millisecond so tests complete quickly
+func fastPolling() *PollingConfig {
+	return &PollingConfig{
+		MaxAttempts:     50,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+		Timeout:         10 * time.Second,
+		FixedInterval:   true,
+	}
+}
+
+// newTestClient creates a client wired to the fake servers. Options are applied
+// after the defaults; the client is closed when the test ends.
+func newTestClient(t *testing.T, runway *fakeRunway, metering *fakeMetering, opts ...Option) *ReveniumRunway {
+	t.Helper()
+	cfg := &Config{
+		RunwayAPIKey:    "key_test",
+		RunwayBaseURL:   runway.URL,
+		RunwayVersion:   "2024-11-06",
+		RequestTimeout:  10 * time.Second,
+		ReveniumAPIKey:  "hak_test",
+		ReveniumBaseURL: metering.URL,
+		PollingConfig:   fastPolling(),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r, err := NewReveniumRunway(cfg)
+	if err != nil {
+		t.Fatalf("NewReveniumRunway() error = %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// fakeClock is a Clock that only moves when advanced. After channels fire
+// immediately, so sleeps never block.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func (c *fakeClock) Since(t time.Time) time.Duration { return c.Now().Sub(t) }
+
+// Advance moves the clock forward by d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
@@ -0,0 +1,71 @@
+package revenium
+
+import "context"
+
+// taskLimiter bounds the number of concurrent Runway tasks, per model where a
+// limit is configured and globally otherwise. A nil taskLimiter imposes no limits.
+type taskLimiter struct {
+	global   chan struct{}            // Slots for models without their own limit (nil = unlimited)
+	perModel map[string]chan struct{} // Slots per model from ConcurrencyByModel
+}
+
+// newTaskLimiter builds a limiter from the configured limits, or returns nil
+// when no limits are configured
+func newTaskLimiter(cfg *Config) *taskLimiter {
+	if cfg.MaxConcurrentTasks <= 0 && len(cfg.ConcurrencyByModel) == 0 {
+		return nil
+	}
+
+	l := &taskLimiter{perModel: make(map[string]chan struct{}, len(cfg.ConcurrencyByModel))}
+	if cfg.MaxConcurrentTasks > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrentTasks)
+	}
+	for model, limit := range cfg.ConcurrencyByModel {
+		if limit > 0 {
+			l.perModel[model] = make(chan struct{}, limit)
+		}
+	}
+	return l
+}
+
+// acquire waits for a task slot for the model and returns a function that
+// releases it. It returns ctx.Err() if ctx is done before a slot frees up.
+func (l *taskLimiter) acquire(ctx context.Context, model string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	sem, ok := l.perModel[model]
+	if !ok {
+		sem = l.global
+	}
+	if sem == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+	default:
+		Debug("Waiting for a concurrency slot for model %s", model)
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() { <-sem }, nil
+}
+
+// sameConcurrencyLimits reports whether two configurations have the same task
+// concurrency limits, so a limiter (and the slots held in it) can be kept
+func sameConcurrencyLimits(a, b *Config) bool {
+	if a.MaxConcurrentTasks != b.MaxConcurrentTasks || len(a.ConcurrencyByModel) != len(b.ConcurrencyByModel) {
+		return false
+	}
+	for model, limit := range a.ConcurrencyByModel {
+		if other, ok := b.ConcurrencyByModel[model]; !ok || other != limit {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package revenium
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskLimiterBlocksAtCapacity(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConcurrentTasks = 1
+	cfg.ConcurrencyByModel = map[string]int{"gen4_turbo": 2}
+	l := newTaskLimiter(cfg)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, "gen3a_turbo")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// The global slot is taken, so the next unlisted model waits
+	acquired := make(chan func(), 1)
+	go func() {
+		next, err := l.acquire(ctx, "gen3a_turbo")
+		if err != nil {
+			t.Errorf("blocked acquire() error = %v", err)
+			return
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire() returned while the only slot was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Models with their own limit do not share the global slot
+	for i := 0; i < 2; i++ {
+		if _, err := l.acquire(ctx, "gen4_turbo"); err != nil {
+			t.Fatalf("per-model acquire() %d error = %v", i+1, err)
+		}
+	}
+
+	release()
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire() did not return after the slot was released")
+	}
+}
+
+func TestTaskLimiterReleasesOnCancel(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConcurrentTasks = 1
+	l := newTaskLimiter(cfg)
+
+	release, err := l.acquire(context.Background(), "gen4_turbo")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx, "gen4_turbo")
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("canceled acquire() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("acquire() did not return after cancellation")
+	}
+
+	// The canceled waiter took no slot: releasing the holder frees the only one
+	release()
+	next, err := l.acquire(context.Background(), "gen4_turbo")
+	if err != nil {
+		t.Fatalf("acquire() after release error = %v", err)
+	}
+	next()
+}
+
+func TestTaskLimiterUnlimited(t *testing.T) {
+	l := newTaskLimiter(testConfig())
+	if l != nil {
+		t.Fatal("newTaskLimiter() without limits returned a limiter")
+	}
+	release, err := l.acquire(context.Background(), "gen4_turbo")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	release()
+}
@@ -1,10 +1,14 @@
 package revenium
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -44,59 +48,99 @@ type Logger interface {
 	GetLevel() LogLevel
 }
 
-// DefaultLogger is the default console logger implementation
+// DefaultTimestampFormat is the default layout for log timestamps
+const DefaultTimestampFormat = "2006-01-02 15:04:05"
+
+// DefaultLogger is the default console logger implementation. It is safe for
+// concurrent use, including changing its settings while other goroutines log.
 type DefaultLogger struct {
-	level LogLevel
+	mu              sync.RWMutex
+	level           LogLevel
+	timestampFormat string
+	utc             bool
 }
 
 // NewDefaultLogger creates a new default logger
 func NewDefaultLogger() *DefaultLogger {
 	return &DefaultLogger{
-		level: LogLevelInfo,
+		level:           LogLevelInfo,
+		timestampFormat: DefaultTimestampFormat,
+	}
+}
+
+// SetTimestampFormat sets the time layout used for log timestamps (e.g. time.RFC3339)
+func (l *DefaultLogger) SetTimestampFormat(layout string) {
+	if layout == "" {
+		layout = DefaultTimestampFormat
 	}
+	l.mu.Lock()
+	l.timestampFormat = layout
+	l.mu.Unlock()
+}
+
+// SetUTC controls whether log timestamps are written in UTC instead of local time
+func (l *DefaultLogger) SetUTC(utc bool) {
+	l.mu.Lock()
+	l.utc = utc
+	l.mu.Unlock()
 }
 
 // SetLevel sets the logging level
 func (l *DefaultLogger) SetLevel(level LogLevel) {
+	l.mu.Lock()
 	l.level = level
+	l.mu.Unlock()
 }
 
 // GetLevel returns the current logging level
 func (l *DefaultLogger) GetLevel() LogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.level
 }
 
 // Debug logs a debug message
 func (l *DefaultLogger) Debug(message string, args ...interface{}) {
-	if l.level <= LogLevelDebug {
+	if l.GetLevel() <= LogLevelDebug {
 		l.log("DEBUG", message, args...)
 	}
 }
 
 // Info logs an info message
 func (l *DefaultLogger) Info(message string, args ...interface{}) {
-	if l.level <= LogLevelInfo {
+	if l.GetLevel() <= LogLevelInfo {
 		l.log("INFO", message, args...)
 	}
 }
 
 // Warn logs a warning message
 func (l *DefaultLogger) Warn(message string, args ...interface{}) {
-	if l.level <= LogLevelWarn {
+	if l.GetLevel() <= LogLevelWarn {
 		l.log("WARN", message, args...)
 	}
 }
 
 // Error logs an error message
 func (l *DefaultLogger) Error(message string, args ...interface{}) {
-	if l.level <= LogLevelError {
+	if l.GetLevel() <= LogLevelError {
 		l.log("ERROR", message, args...)
 	}
 }
 
 // log is the internal logging method
 func (l *DefaultLogger) log(level, message string, args ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	l.mu.RLock()
+	utc, layout := l.utc, l.timestampFormat
+	l.mu.RUnlock()
+
+	now := time.Now()
+	if utc {
+		now = now.UTC()
+	}
+	if layout == "" {
+		layout = DefaultTimestampFormat
+	}
+	timestamp := now.Format(layout)
 	prefix := fmt.Sprintf("[%s] [Revenium Runway %s]", timestamp, level)
 
 	if len(args) > 0 {
@@ -163,6 +207,30 @@ func Error(message string, args ...interface{}) {
 	globalLogger.Error(message, args...)
 }
 
+// logRequest writes a one-line access log for an outbound HTTP call when
+// WithRequestLogging is enabled. Only the method, host, path, status and
+// latency are logged; query strings and headers (including credentials) never are.
+func logRequest(cfg *Config, service string, req *http.Request, statusCode int, start time.Time, err error) {
+	if !cfg.RequestLogging {
+		return
+	}
+
+	latency := cfg.clock().Since(start).Round(time.Millisecond)
+	target := req.URL.Host + req.URL.Path
+	prefix := logPrefix(correlationIDFrom(req.Context()))
+
+	if err != nil {
+		// url.Error repeats the full URL; log only the underlying cause
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		Info("%s[HTTP] %s %s %s failed latency=%v error=%v", prefix, service, req.Method, target, latency, err)
+		return
+	}
+	Info("%s[HTTP] %s %s %s status=%d latency=%v", prefix, service, req.Method, target, statusCode, latency)
+}
+
 // ParseLogLevel parses a string log level to LogLevel
 func ParseLogLevel(level string) LogLevel {
 	switch strings.ToUpper(level) {
@@ -0,0 +1,96 @@
+package revenium
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useDefaultLogger installs a fresh DefaultLogger writing to io.Discard and
+// restores the global state when the test ends
+func useDefaultLogger(t *testing.T) *DefaultLogger {
+	t.Helper()
+	saved := SaveState()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		Reset()
+		RestoreState(saved)
+	})
+	logger := NewDefaultLogger()
+	SetLogger(logger)
+	return logger
+}
+
+func loggerSettings(l *DefaultLogger) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.timestampFormat, l.utc
+}
+
+func TestNewReveniumRunwayLeavesLoggerAlone(t *testing.T) {
+	logger := useDefaultLogger(t)
+	runway, metering := newFakeRunway(t), newFakeMetering(t)
+	newTestClient(t, runway, metering, WithLogTimestampFormat(time.RFC3339), WithLogUTC(true))
+
+	if layout, utc := loggerSettings(logger); layout != DefaultTimestampFormat || utc {
+		t.Errorf("logger settings = %q, utc %v; want the defaults", layout, utc)
+	}
+}
+
+func TestGlobalClientAppliesLoggerSettings(t *testing.T) {
+	logger := useDefaultLogger(t)
+	runway, metering := newFakeRunway(t), newFakeMetering(t)
+	t.Setenv("RUNWAY_API_KEY", "key_test")
+	t.Setenv("RUNWAY_BASE_URL", runway.URL)
+	t.Setenv("REVENIUM_METERING_API_KEY", "hak_test")
+	t.Setenv("REVENIUM_METERING_BASE_URL", metering.URL)
+	Reset()
+	if err := Initialize(WithLogTimestampFormat(time.RFC3339), WithLogUTC(true)); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if layout, utc := loggerSettings(logger); layout != time.RFC3339 || !utc {
+		t.Errorf("after Initialize: logger settings = %q, utc %v; want %q, utc true", layout, utc, time.RFC3339)
+	}
+
+	client, err := GetClient()
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	if err := client.Reconfigure(WithLogTimestampFormat(""), WithLogUTC(false)); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	if layout, utc := loggerSettings(logger); layout != DefaultTimestampFormat || utc {
+		t.Errorf("after Reconfigure: logger settings = %q, utc %v; want the defaults", layout, utc)
+	}
+}
+
+// TestDefaultLoggerConcurrentSettings changes the settings while other
+// goroutines log; run with -race
+func TestDefaultLoggerConcurrentSettings(t *testing.T) {
+	logger := useDefaultLogger(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("message %d", j)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.SetUTC(j%2 == 0)
+				logger.SetTimestampFormat(fmt.Sprintf("%s %d", time.RFC3339, i))
+				logger.SetLevel(LogLevelInfo)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
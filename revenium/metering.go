@@ -3,32 +3,45 @@ package revenium
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	mathrand "math/rand"
 	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // MaxPromptLength is the maximum length for captured prompts
 const MaxPromptLength = 50000
 
-// formatPromptAsInputMessages formats a single prompt string as JSON inputMessages
-// for compatibility with the Revenium dashboard's unified prompt view.
-// Format: [{"role": "user", "content": "<prompt>"}]
-func formatPromptAsInputMessages(prompt string) (string, bool) {
-	if prompt == "" {
+// formatPromptAsInputMessages formats the prompt, and the negative prompt if
+// any, as JSON inputMessages for compatibility with the Revenium dashboard's
+// unified prompt view. Each prompt is truncated to MaxPromptLength.
+// Format: [{"role": "user", "content": "<prompt>"}, {"role": "negative", "content": "<negative prompt>"}]
+func formatPromptAsInputMessages(prompt, negative string) (string, bool) {
+	if prompt == "" && negative == "" {
 		return "", false
 	}
 
 	truncated := false
-	if len(prompt) > MaxPromptLength {
-		prompt = prompt[:MaxPromptLength] + "...[TRUNCATED]"
-		truncated = true
-	}
-
-	messages := []map[string]string{
-		{"role": "user", "content": prompt},
+	messages := make([]map[string]string, 0, 2)
+	for _, m := range []struct{ role, content string }{{"user", prompt}, {"negative", negative}} {
+		if m.content == "" {
+			continue
+		}
+		if len(m.content) > MaxPromptLength {
+			m.content = m.content[:MaxPromptLength] + "...[TRUNCATED]"
+			truncated = true
+		}
+		messages = append(messages, map[string]string{"role": m.role, "content": m.content})
 	}
 
 	jsonBytes, err := json.Marshal(messages)
@@ -40,42 +53,254 @@ func formatPromptAsInputMessages(prompt string) (string, bool) {
 	return string(jsonBytes), truncated
 }
 
-// Package-level HTTP client with connection pooling for metering requests.
-// This prevents creating a new client for each metering call, avoiding
-// file descriptor exhaustion and TCP handshake overhead under high load.
-var meteringHTTPClient = &http.Client{
-	Timeout: 10 * time.Second,
-	Transport: &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		DisableCompression:  true, // JSON is already small
-	},
+// warnSubscriberOverlap warns about Custom keys that also appear in
+// Subscriber; both end up in the payload (Custom at the top level, Subscriber
+// nested), which makes analytics ambiguous
+func warnSubscriberOverlap(metadata *UsageMetadata) {
+	var overlap []string
+	for k := range metadata.Custom {
+		if _, ok := metadata.Subscriber[k]; ok {
+			overlap = append(overlap, k)
+		}
+	}
+	if len(overlap) > 0 {
+		sort.Strings(overlap)
+		Warn("Custom keys also present in Subscriber: %s (use WithCustomNamespace to nest custom fields)", strings.Join(overlap, ", "))
+	}
+}
+
+// capturePrompts reports whether prompts are captured for an operation: the
+// metadata's CapturePrompts when set, the configured default otherwise
+func capturePrompts(cfg *Config, metadata *UsageMetadata) bool {
+	if metadata != nil && metadata.CapturePrompts != nil {
+		return *metadata.CapturePrompts
+	}
+	return cfg.CapturePrompts
 }
 
+// Package-level transport with connection pooling for metering requests.
+// Every MeteringClient shares it, avoiding file descriptor exhaustion and TCP
+// handshake overhead under high load; only the timeout differs per client.
+var meteringTransport = &http.Transport{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+	DisableCompression:  true, // JSON is already small
+}
+
+// Lifecycle record values emitted with WithLifecycleMetering
+const (
+	lifecycleEventStart    = "START"
+	lifecycleEventComplete = "COMPLETE"
+	stopReasonInProgress   = "IN_PROGRESS"
+)
+
 // MeteringClient handles communication with the Revenium metering API
 type MeteringClient struct {
-	config *Config
+	sequence uint64 // Last sequence number assigned to a metering record (first for 64-bit alignment)
+	config   *Config
+	nonce    string          // Random identifier for this client instance, sent as clientNonce
+	breaker  *circuitBreaker // Fast-fails sends while Revenium is unreachable
+	http     *http.Client    // Uses the shared meteringTransport with the configured timeout
 }
 
 // NewMeteringClient creates a new metering client
 func NewMeteringClient(config *Config) *MeteringClient {
+	timeout := config.MeteringTimeout
+	if timeout <= 0 {
+		timeout = DefaultMeteringTimeout
+	}
+
 	return &MeteringClient{
-		config: config,
+		config:  config,
+		nonce:   newClientNonce(),
+		breaker: newCircuitBreaker(config),
+		http:    &http.Client{Timeout: timeout, Transport: meteringTransport},
+	}
+}
+
+// CircuitState returns the state of the client's metering circuit breaker
+func (m *MeteringClient) CircuitState() CircuitState {
+	return m.breaker.currentState()
+}
+
+// newClientNonce generates a random identifier for a metering client instance
+func newClientNonce() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
 	}
+	return hex.EncodeToString(b)
 }
 
-// SendVideoMetering sends video generation metering data to Revenium
+// SendVideoMetering sends video generation metering data to Revenium. With
+// WithPerOutputMetering, one record is sent per output and the errors of any
+// failed records are joined.
 func (m *MeteringClient) SendVideoMetering(ctx context.Context, result *VideoGenerationResult, metadata *UsageMetadata) error {
-	payload := m.buildMeteringPayload(result, metadata)
+	baseURL := meteringBaseURL(metadata)
 
 	// Send with retry logic
-	return m.sendWithRetry(ctx, payload)
+	var errs []error
+	for _, payload := range m.preparePayloads(result, metadata) {
+		if err := m.sendWithRetry(ctx, payload, baseURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// preparePayloads returns the metering records for a result: a single record,
+// or with WithPerOutputMetering one record per output when the operation
+// produced several. Per-output records get their own transactionId
+// ("<taskId>-<n>") and point to the task ID as parentTransactionId, replacing
+// a caller-supplied UsageMetadata.ParentTransactionID; the replacement is
+// logged, and traceId still links the records to the caller's trace. The
+// billing fields are split across the records (see splitBilling), so together
+// they bill the operation once.
+func (m *MeteringClient) preparePayloads(result *VideoGenerationResult, metadata *UsageMetadata) []map[string]interface{} {
+	payload := m.preparePayload(result, metadata)
+	if !m.config.MeterPerOutput || len(result.OutputURLs) <= 1 {
+		return []map[string]interface{}{payload}
+	}
+	if parent, _ := payload["parentTransactionId"].(string); parent != "" {
+		Warn("%sPer-output records for task %s replace parentTransactionId %q with the task ID", logPrefix(result.CorrelationID), result.ID, parent)
+	}
+
+	count := len(result.OutputURLs)
+	payloads := make([]map[string]interface{}, count)
+	for i := range payloads {
+		record := payload
+		if i > 0 {
+			record = make(map[string]interface{}, len(payload)+4)
+			for k, v := range payload {
+				record[k] = v
+			}
+			// Every record needs its own sequence number for gap detection
+			record["sequence"] = atomic.AddUint64(&m.sequence, 1)
+		}
+		record["transactionId"] = fmt.Sprintf("%s-%d", result.ID, i+1)
+		record["parentTransactionId"] = result.ID
+		record["outputIndex"] = i
+		record["outputCount"] = count
+		payloads[i] = record
+	}
+	splitBilling(payloads)
+	return payloads
+}
+
+// splitBillingFields are the fractional payload fields that bill an operation
+var splitBillingFields = []string{"durationSeconds", "requestedDurationSeconds", "estimatedCredits", "estimatedCost"}
+
+// splitBilling divides the billing fields of an operation's payload across its
+// per-output records, which start as copies of it. Fractional fields are split
+// evenly, with the last record taking the rounding remainder; the integer
+// quantity goes to the first records, one each.
+func splitBilling(records []map[string]interface{}) {
+	n := len(records)
+	for _, key := range splitBillingFields {
+		total, ok := records[0][key].(float64)
+		if !ok {
+			continue
+		}
+		share := total / float64(n)
+		assigned := 0.0
+		for i, record := range records {
+			if i == n-1 {
+				record[key] = total - assigned
+				continue
+			}
+			record[key] = share
+			assigned += share
+		}
+	}
+
+	if quantity, ok := records[0]["quantity"].(int); ok {
+		for i, record := range records {
+			share := quantity / n
+			if i < quantity%n {
+				share++
+			}
+			record["quantity"] = share
+		}
+	}
+}
+
+// prepareStartPayload builds the lifecycle start record for a task that has
+// just been created. It carries the request details but bills nothing: the
+// duration is 0 and quantity and cost estimates are omitted.
+func (m *MeteringClient) prepareStartPayload(result *VideoGenerationResult, metadata *UsageMetadata) map[string]interface{} {
+	payload := m.preparePayload(result, metadata)
+	payload["stopReason"] = stopReasonInProgress
+	payload["lifecycleEvent"] = lifecycleEventStart
+	payload["durationSeconds"] = 0.0
+	for _, key := range []string{"quantity", "estimatedCredits", "estimatedCost", "currency", "durationUnknown"} {
+		delete(payload, key)
+	}
+	return payload
+}
+
+// meteringBaseURL returns the normalized per-record base URL override from the
+// metadata, or "" to use the configured base URL
+func meteringBaseURL(metadata *UsageMetadata) string {
+	if metadata == nil || metadata.MeteringBaseURL == "" {
+		return ""
+	}
+	return NormalizeReveniumBaseURL(metadata.MeteringBaseURL)
+}
+
+// preparePayload builds the metering payload and applies configured sanitization.
+// Timestamps are fixed at this point, so a payload sent later keeps them.
+func (m *MeteringClient) preparePayload(result *VideoGenerationResult, metadata *UsageMetadata) map[string]interface{} {
+	payload := m.buildMeteringPayload(result, metadata, atomic.AddUint64(&m.sequence, 1))
+	if m.config.SanitizeCustomFields {
+		sanitizePayload(payload)
+	}
+	return payload
+}
+
+// BuildMeteringPayload returns the metering record SendVideoMetering would
+// send for result, without sending it, for debugging and compliance review. It
+// does not consume a sequence number: the record carries the number the next
+// sent record will get. Keys use the standard field names; renames from
+// WithMeteringFieldRenamer and size trimming are applied only when sending.
+func (m *MeteringClient) BuildMeteringPayload(result *VideoGenerationResult, metadata *UsageMetadata) map[string]interface{} {
+	payload := m.buildMeteringPayload(result, metadata, atomic.LoadUint64(&m.sequence)+1)
+	if m.config.SanitizeCustomFields {
+		sanitizePayload(payload)
+	}
+	return payload
+}
+
+// defaultDurationSeconds is the duration metered, per operation, when the
+// result reports none. Generation defaults to Runway's 5 second clip; upscales
+// meter the source duration recorded by addUpscaleMetadata, or 0 with
+// durationUnknown. Operations missing here have no meaningful duration and
+// their records omit durationSeconds.
+var defaultDurationSeconds = map[string]float64{
+	OperationImageToVideo: 5,
+	OperationVideoToVideo: 5,
+	OperationTextToVideo:  5,
+	OperationVideoUpscale: 0,
+}
+
+// defaultDuration returns the default duration for an operation and whether it
+// has one. Results without an operation, such as those passed directly to
+// SendVideoMetering, keep the Runway 5 second default.
+func defaultDuration(operation string) (float64, bool) {
+	if operation == "" {
+		return 5, true
+	}
+	seconds, ok := defaultDurationSeconds[operation]
+	return seconds, ok
 }
 
 // buildMeteringPayload constructs the metering payload for video generation
-func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, metadata *UsageMetadata) map[string]interface{} {
-	now := time.Now()
+// with the given sequence number
+func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, metadata *UsageMetadata, sequence uint64) map[string]interface{} {
+	now := m.config.clock().Now()
 	requestTime := now.Add(-result.Duration)
 
 	// Determine stop reason
@@ -86,48 +311,150 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 		stopReason = "CANCELLED"
 	}
 
-	// Extract video duration from metadata if available (default to 5 seconds for gen3a_turbo)
-	var videoDurationSeconds float64 = 5.0 // Runway default
-	var requestedDurationSeconds float64 = 5.0 // Runway default requested duration
-	if result.Metadata != nil {
+	// Extract video duration from metadata if available, falling back to the
+	// operation's default
+	operation, _ := result.Metadata["operationSubtype"].(string)
+	videoDurationSeconds, hasDuration := defaultDuration(operation)
+	requestedDurationSeconds := videoDurationSeconds
+	if result.Metadata != nil || result.RequestedDuration > 0 {
+		delivered := true
 		if dur, ok := result.Metadata["duration"].(int); ok {
 			videoDurationSeconds = float64(dur)
 		} else if dur, ok := result.Metadata["duration"].(float64); ok {
 			videoDurationSeconds = dur
 		} else if dur, ok := result.Metadata["durationSeconds"].(float64); ok {
 			videoDurationSeconds = dur
+		} else {
+			delivered = false
 		}
+		hasDuration = hasDuration || delivered
 		// Extract requested duration for per-second billing
+		requested := true
 		if reqDur, ok := result.Metadata["requestedDuration"].(int); ok {
 			requestedDurationSeconds = float64(reqDur)
 		} else if reqDur, ok := result.Metadata["requestedDuration"].(float64); ok {
 			requestedDurationSeconds = reqDur
 		} else if reqDur, ok := result.Metadata["requestedDurationSeconds"].(float64); ok {
 			requestedDurationSeconds = reqDur
+		} else if result.RequestedDuration > 0 {
+			requestedDurationSeconds = float64(result.RequestedDuration)
 		} else {
 			// Default to actual duration if requested not specified
 			requestedDurationSeconds = videoDurationSeconds
+			requested = false
 		}
+		// Without a delivered duration, bill the requested one rather than the default
+		if !delivered && requested {
+			videoDurationSeconds = requestedDurationSeconds
+		}
+		hasDuration = hasDuration || requested
 	}
 
 	// Build base payload with durationSeconds at TOP LEVEL for billing (per API contract)
 	payload := map[string]interface{}{
 		"operationType":            "VIDEO",
-		"provider":                 "runway",
-		"modelSource":              "RUNWAY",
+		"provider":                 m.config.provider(),
+		"modelSource":              m.config.modelSource(),
 		"model":                    result.Model,
 		"transactionId":            result.ID,
 		"requestTime":              requestTime.Format(time.RFC3339),
 		"responseTime":             now.Format(time.RFC3339),
 		"requestDuration":          result.Duration.Milliseconds(),
-		"durationSeconds":          videoDurationSeconds,          // CRITICAL: actual video duration for billing
-		"requestedDurationSeconds": requestedDurationSeconds,      // CRITICAL: requested duration for per-second billing
+		"durationSeconds":          videoDurationSeconds,     // CRITICAL: actual video duration for billing
+		"requestedDurationSeconds": requestedDurationSeconds, // CRITICAL: requested duration for per-second billing
 		"stopReason":               stopReason,
 		"costType":                 "AI",
 		"isStreamed":               false,
 		"middlewareSource":         GetMiddlewareSource(),
 	}
 
+	// Operations without a natural duration report none rather than a default
+	if !hasDuration {
+		delete(payload, "durationSeconds")
+		delete(payload, "requestedDurationSeconds")
+	}
+
+	// Add a per-client sequence number so the backend can detect dropped or
+	// reordered records. The sequence is assigned once per record (retries reuse
+	// it) and is scoped to clientNonce: it restarts at 1 for every new
+	// MeteringClient, including after Reconfigure and in each process.
+	payload["sequence"] = sequence
+	payload["clientNonce"] = m.nonce
+
+	// Add billing basis so the backend knows whether to price by duration or per task.
+	// PER_SECOND: price = durationSeconds * per-second rate
+	// PER_GENERATION: price = quantity * per-generation rate (durationSeconds is informational)
+	billingBasis := m.config.GetBillingBasis(result.Model)
+	payload["billingBasis"] = string(billingBasis)
+	if billingBasis == BillingBasisPerGeneration {
+		payload["quantity"] = 1
+	}
+
+	// Add a cost estimate when the model has configured pricing
+	if pricing, ok := m.config.PricingTable[result.Model]; ok {
+		credits := pricing.CreditsPerSecond * videoDurationSeconds
+		if billingBasis == BillingBasisPerGeneration {
+			credits = pricing.CreditsPerGeneration
+		}
+		payload["estimatedCredits"] = credits
+		if pricing.CostPerCredit > 0 {
+			payload["estimatedCost"] = credits * pricing.CostPerCredit
+			if pricing.Currency != "" {
+				payload["currency"] = pricing.Currency
+			}
+		}
+	}
+
+	// Attribute usage to the host application's release
+	if m.config.AppVersion != "" {
+		payload["appVersion"] = m.config.AppVersion
+	}
+
+	// Mark the final record of a lifecycle pair
+	if m.config.LifecycleMetering {
+		payload["lifecycleEvent"] = lifecycleEventComplete
+	}
+
+	// Tie the record to the Runway requests made for this operation
+	if result.CorrelationID != "" {
+		payload["correlationId"] = result.CorrelationID
+	}
+
+	// Flag records whose video duration could not be determined (durationSeconds is 0)
+	durationKnown := hasDuration && videoDurationSeconds > 0
+	if unknown, ok := result.Metadata["durationUnknown"].(bool); ok && unknown {
+		payload["durationUnknown"] = true
+		durationKnown = false
+	}
+
+	// Add frame information, deriving whichever of fps and frameCount Runway
+	// did not report from the other and the duration
+	fps, frameCount := result.FPS, result.FrameCount
+	if fps > 0 && frameCount == 0 && durationKnown {
+		frameCount = int(math.Round(videoDurationSeconds * fps))
+		payload["frameCountDerived"] = true
+	} else if frameCount > 0 && fps == 0 && durationKnown {
+		fps = float64(frameCount) / videoDurationSeconds
+		payload["fpsDerived"] = true
+	}
+	if fps > 0 {
+		payload["fps"] = fps
+	}
+	if frameCount > 0 {
+		payload["frameCount"] = frameCount
+	}
+
+	// Add the number of status polls, for tuning polling intervals
+	if result.PollCount > 0 {
+		payload["pollCount"] = result.PollCount
+	}
+
+	// Add provider transfer sizes when enabled
+	if m.config.EmitByteCounts {
+		payload["requestBytes"] = result.RequestBytes
+		payload["responseBytes"] = result.ResponseBytes
+	}
+
 	// Add error information if failed
 	if result.Error != nil {
 		payload["errorReason"] = *result.Error
@@ -137,9 +464,22 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 		payload["failureCode"] = *result.FailureCode
 	}
 
+	// Let business rules override the derived stop reason (e.g. to exclude
+	// safety rejections from billed usage)
+	if classify := m.config.StopReasonClassifier; classify != nil {
+		if reason := classify(result); reason != "" {
+			payload["stopReason"] = reason
+		}
+	}
+
 	// Add metadata from result
 	if result.Metadata != nil {
 		for k, v := range result.Metadata {
+			// Underscore keys carry internal state (e.g. captured prompts, which
+			// are sent truncated as inputMessages)
+			if strings.HasPrefix(k, "_") {
+				continue
+			}
 			// Only add if not already in payload
 			if _, exists := payload[k]; !exists {
 				payload[k] = v
@@ -185,6 +525,10 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 		}
 		if metadata.RetryNumber != nil {
 			payload["retryNumber"] = *metadata.RetryNumber
+			// Link retries to the first attempt so the backend can collapse them
+			if *metadata.RetryNumber > 0 && metadata.OriginalTransactionID != "" {
+				payload["originalTransactionId"] = metadata.OriginalTransactionID
+			}
 		}
 		if metadata.CredentialAlias != "" {
 			payload["credentialAlias"] = metadata.CredentialAlias
@@ -205,8 +549,25 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 		if metadata.AudioJobID != "" {
 			payload["audioJobId"] = metadata.AudioJobID
 		}
-		if metadata.Custom != nil {
-			for k, v := range metadata.Custom {
+		custom, removed := m.config.filterCustomKeys(metadata.Custom)
+		if len(removed) > 0 {
+			Warn("Dropped custom keys not in the allowlist: %s", strings.Join(removed, ", "))
+		}
+		if custom != nil && m.config.CustomNamespace {
+			// Keep custom fields apart from standard and subscriber fields
+			namespaced := make(map[string]interface{}, len(custom))
+			for k, v := range custom {
+				namespaced[k] = v
+			}
+			payload["custom"] = namespaced
+		} else if custom != nil {
+			warnSubscriberOverlap(metadata)
+			for k, v := range custom {
+				// Never let custom fields shadow middleware fields
+				if IsReservedPayloadKey(k) {
+					Debug("Ignoring custom field %q: reserved metering key", k)
+					continue
+				}
 				// Only add if not already in payload
 				if _, exists := payload[k]; !exists {
 					payload[k] = v
@@ -216,18 +577,20 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 	}
 
 	// Add prompt capture fields when enabled (opt-in)
-	if m.config.CapturePrompts {
+	if capturePrompts(m.config, metadata) {
 		// Check for prompt in result metadata (stored by middleware)
 		if result.Metadata != nil {
-			if prompt, ok := result.Metadata["_capturedPrompt"].(string); ok && prompt != "" {
-				inputMessages, truncated := formatPromptAsInputMessages(prompt)
+			prompt, _ := result.Metadata["_capturedPrompt"].(string)
+			negative, _ := result.Metadata["_capturedNegativePrompt"].(string)
+			if prompt != "" || negative != "" {
+				inputMessages, truncated := formatPromptAsInputMessages(prompt, negative)
 				if inputMessages != "" {
 					payload["inputMessages"] = inputMessages
 				}
 				if truncated {
 					payload["promptsTruncated"] = true
 				}
-				Debug("Prompt capture enabled: captured %d chars", len(prompt))
+				Debug("Prompt capture enabled: captured %d chars (negative: %d chars)", len(prompt), len(negative))
 			}
 			// Add output URLs if available
 			if len(result.OutputURLs) > 0 {
@@ -242,56 +605,309 @@ func (m *MeteringClient) buildMeteringPayload(result *VideoGenerationResult, met
 	return payload
 }
 
-// sendWithRetry sends metering data with exponential backoff retry
-func (m *MeteringClient) sendWithRetry(ctx context.Context, payload map[string]interface{}) error {
+// sanitizePayload makes the payload safe to marshal by replacing or dropping
+// values encoding/json rejects (channels, funcs, cyclic or otherwise invalid
+// values). Nested maps and slices are sanitized element by element so one bad
+// entry does not discard its siblings.
+func sanitizePayload(payload map[string]interface{}) {
+	for key, value := range payload {
+		sanitized, ok := sanitizeValue(key, value, 0)
+		if ok {
+			payload[key] = sanitized
+		} else {
+			delete(payload, key)
+		}
+	}
+}
+
+// maxSanitizeDepth bounds recursion into nested values (guards against cycles)
+const maxSanitizeDepth = 32
+
+// sanitizeValue returns a JSON-safe form of value, or false if it must be dropped
+func sanitizeValue(path string, value interface{}, depth int) (interface{}, bool) {
+	if _, err := json.Marshal(value); err == nil {
+		return value, true
+	}
+	if depth >= maxSanitizeDepth {
+		Warn("Metering field %q is nested too deeply to sanitize; dropping it", path)
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		clean := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if sanitized, ok := sanitizeValue(path+"."+k, item, depth+1); ok {
+				clean[k] = sanitized
+			}
+		}
+		return clean, true
+	case []interface{}:
+		clean := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			if sanitized, ok := sanitizeValue(fmt.Sprintf("%s[%d]", path, i), item, depth+1); ok {
+				clean = append(clean, sanitized)
+			}
+		}
+		return clean, true
+	case fmt.Stringer:
+		Warn("Metering field %q is not JSON-serializable (%T); sending its string form", path, value)
+		return v.String(), true
+	case error:
+		Warn("Metering field %q is not JSON-serializable (%T); sending its error message", path, value)
+		return v.Error(), true
+	}
+
+	Warn("Metering field %q is not JSON-serializable (%T); dropping it", path, value)
+	return nil, false
+}
+
+// marshalPayload serializes a payload for sending, applying the configured
+// field renames to its top-level keys
+func (m *MeteringClient) marshalPayload(payload map[string]interface{}) ([]byte, error) {
+	if len(m.config.MeteringFieldNames) == 0 {
+		return json.Marshal(payload)
+	}
+	renamed := make(map[string]interface{}, len(payload))
+	for k, v := range payload {
+		if _, ok := m.config.MeteringFieldNames[k]; !ok {
+			renamed[k] = v
+		}
+	}
+	// Renamed fields win over fields that already had the target name
+	for from, to := range m.config.MeteringFieldNames {
+		if v, ok := payload[from]; ok {
+			renamed[to] = v
+		}
+	}
+	return json.Marshal(renamed)
+}
+
+// fitPayload enforces the payload size limit on marshaled data. Under
+// PayloadSizeTrim it drops captured prompts and custom fields, largest first,
+// from a copy of the payload until it fits; under PayloadSizeReject, or when
+// nothing droppable is left, it returns a ValidationError.
+func (m *MeteringClient) fitPayload(payload map[string]interface{}, data []byte) ([]byte, error) {
+	limit := m.config.MaxPayloadBytes
+	if limit <= 0 {
+		limit = DefaultMaxPayloadBytes
+	}
+	if len(data) <= limit {
+		return data, nil
+	}
+
+	tooLarge := func() error {
+		return NewValidationError(fmt.Sprintf("metering payload is %d bytes, limit is %d", len(data), limit), nil).
+			WithDetails("transactionId", payload["transactionId"])
+	}
+	if m.config.PayloadSizePolicy == PayloadSizeReject {
+		return nil, tooLarge()
+	}
+
+	// Droppable fields are the captured prompt and output, and custom fields
+	// (the custom object, or every key the middleware does not emit itself)
+	type field struct {
+		key  string
+		size int
+	}
+	var droppable []field
+	for k, v := range payload {
+		if k != "inputMessages" && k != "outputResponse" && k != "custom" && IsReservedPayloadKey(k) {
+			continue
+		}
+		encoded, _ := json.Marshal(v)
+		droppable = append(droppable, field{k, len(k) + len(encoded)})
+	}
+	sort.Slice(droppable, func(i, j int) bool {
+		if droppable[i].size != droppable[j].size {
+			return droppable[i].size > droppable[j].size
+		}
+		return droppable[i].key < droppable[j].key
+	})
+
+	trimmed := make(map[string]interface{}, len(payload)+1)
+	for k, v := range payload {
+		trimmed[k] = v
+	}
+	trimmed["payloadTrimmed"] = true
+
+	var dropped []string
+	for _, f := range droppable {
+		delete(trimmed, f.key)
+		dropped = append(dropped, f.key)
+		trimmedData, err := m.marshalPayload(trimmed)
+		if err != nil {
+			return nil, NewMeteringError("failed to marshal metering payload", err)
+		}
+		if len(trimmedData) <= limit {
+			Warn("Metering payload for %v was %d bytes (limit %d); dropped fields: %s",
+				payload["transactionId"], len(data), limit, strings.Join(dropped, ", "))
+			return trimmedData, nil
+		}
+	}
+	return nil, tooLarge()
+}
+
+// sendWithRetry sends metering data with exponential backoff retry, unless the
+// circuit breaker is open. baseURL overrides the configured base URL when set;
+// the circuit breaker only tracks the configured endpoint.
+func (m *MeteringClient) sendWithRetry(ctx context.Context, payload map[string]interface{}, baseURL string) (err error) {
+	if callback := m.config.MeteringCallback; callback != nil {
+		defer func() { runMeteringCallback(callback, payload, err) }()
+	}
+
+	if baseURL != "" {
+		return m.sendAttempts(ctx, payload, baseURL)
+	}
+
+	if !m.breaker.allow() {
+		return NewMeteringError("metering circuit open; record dropped", nil).
+			WithDetails("circuitState", string(m.breaker.currentState()))
+	}
+
+	err = m.sendAttempts(ctx, payload, "")
+	switch {
+	case err == nil || (IsValidationError(err) && statusCodeOf(err) != 0):
+		// Revenium responded, so the endpoint is healthy
+		m.breaker.record(true)
+	case ctx.Err() != nil || IsConfigError(err) || IsValidationError(err):
+		m.breaker.release()
+	default:
+		m.breaker.record(false)
+	}
+	return err
+}
+
+// runMeteringCallback invokes the MeteringCallback, recovering from panics so
+// a faulty callback cannot crash a background metering goroutine
+func runMeteringCallback(callback MeteringCallback, payload map[string]interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			Error("MeteringCallback panic for transaction %v: %v", payload["transactionId"], rec)
+		}
+	}()
+	callback(payload, err)
+}
+
+// sendAttempts makes up to three send attempts with exponential backoff
+func (m *MeteringClient) sendAttempts(ctx context.Context, payload map[string]interface{}, baseURL string) error {
 	const maxRetries = 3
 	const initialBackoff = 100 * time.Millisecond
 
 	var lastErr error
 	backoff := initialBackoff
+	var override time.Duration
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			time.Sleep(backoff)
-			backoff *= 2 // Exponential backoff
+			wait := backoff
+			if override > 0 {
+				wait = override
+			} else {
+				if !m.config.NoMeteringJitter {
+					wait = jitter(backoff)
+				}
+				backoff *= 2 // Exponential backoff
+			}
+			// Stop retrying when the context is canceled (e.g. on shutdown); the
+			// error matches ctx.Err() with errors.Is
+			if err := sleepContext(ctx, m.config.clock(), wait); err != nil {
+				return NewMeteringError("metering retries canceled", errors.Join(err, lastErr))
+			}
 		}
 
-		err := m.sendMeteringRequest(ctx, payload)
+		err := m.sendMeteringRequest(ctx, payload, baseURL)
 		if err == nil {
 			return nil // Success
 		}
 
 		lastErr = err
 
-		// Don't retry on validation errors
-		if IsValidationError(err) {
+		retry, delay := m.classifyRetry(err)
+		if !retry {
 			return err
 		}
+		override = delay
 	}
 
 	return NewMeteringError("metering failed after retries", lastErr)
 }
 
-// sendMeteringRequest sends a single metering request to Revenium API
-func (m *MeteringClient) sendMeteringRequest(ctx context.Context, payload map[string]interface{}) error {
+// jitter returns a random duration in [d/2, d] ("equal jitter"), keeping
+// half the backoff while spreading out concurrent retries
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + time.Duration(mathrand.Int63n(int64(half)+1))
+}
+
+// jitterInterval returns d randomized uniformly within ±fraction of itself
+func jitterInterval(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 - fraction + 2*fraction*mathrand.Float64()))
+}
+
+// classifyRetry consults the custom RetryClassifier, falling back to
+// DefaultRetryClassifier when there is none or it returns DeferRetry
+func (m *MeteringClient) classifyRetry(err error) (bool, time.Duration) {
+	statusCode := statusCodeOf(err)
+	if classify := m.config.RetryClassifier; classify != nil {
+		if retry, backoff := classify(statusCode, err); backoff != DeferRetry {
+			return retry, backoff
+		}
+	}
+	return DefaultRetryClassifier(statusCode, err)
+}
+
+// DefaultRetryClassifier is the built-in metering retry policy: validation errors
+// (4xx responses) are not retried, everything else is retried with exponential backoff.
+// It is used for failures a custom RetryClassifier defers with DeferRetry.
+func DefaultRetryClassifier(statusCode int, err error) (bool, time.Duration) {
+	if IsValidationError(err) {
+		return false, 0
+	}
+	return true, 0
+}
+
+// statusCodeOf returns the HTTP status code carried by a metering error, or 0
+// when the request never received a response
+func statusCodeOf(err error) int {
+	var revErr *ReveniumError
+	if errors.As(err, &revErr) {
+		return revErr.StatusCode
+	}
+	return 0
+}
+
+// sendMeteringRequest sends a single metering request to Revenium API, using
+// the configured base URL unless baseURL is set
+func (m *MeteringClient) sendMeteringRequest(ctx context.Context, payload map[string]interface{}, baseURL string) error {
 	if m.config.ReveniumAPIKey == "" {
 		return NewConfigError("Revenium API key not configured", nil)
 	}
 
 	// Build request URL - note: video endpoint is /meter/v2/ai/video
-	baseURL := m.config.ReveniumBaseURL
 	if baseURL == "" {
-		baseURL = "https://api.revenium.ai"
+		baseURL = m.config.reveniumBaseURL()
 	}
 	url := baseURL + "/meter/v2/ai/video"
 
-	// Marshal payload to JSON
-	jsonData, err := json.Marshal(payload)
+	// Marshal payload to JSON. encoding/json writes map keys (at every depth) in
+	// sorted order, so the same payload always produces the same bytes, as
+	// signature-verifying proxies require; no ordered struct is needed.
+	jsonData, err := m.marshalPayload(payload)
 	if err != nil {
 		return NewMeteringError("failed to marshal metering payload", err)
 	}
+	if jsonData, err = m.fitPayload(payload, jsonData); err != nil {
+		return err
+	}
 
-	Debug("[METERING] Sending video metering to %s: %s", url, string(jsonData))
+	Debug("[METERING] Sending video metering to %s: %s", url, truncateForLog(jsonData, m.config.MeteringMaxBodyLogBytes))
 
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
@@ -300,15 +916,18 @@ func (m *MeteringClient) sendMeteringRequest(ctx context.Context, payload map[st
 	}
 
 	// Set headers
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Content-Type", m.config.meteringContentType())
 	req.Header.Set("x-api-key", m.config.ReveniumAPIKey)
 	req.Header.Set("User-Agent", "revenium-middleware-runway-go/1.0")
 
-	// Send request using pooled client (avoids creating new client per instance)
-	resp, err := meteringHTTPClient.Do(req)
+	// Send request over the pooled transport
+	start := m.config.clock().Now()
+	resp, err := m.http.Do(req)
 	if err != nil {
+		logRequest(m.config, "revenium", req, 0, start, err)
 		return NewNetworkError("metering request failed", err)
 	}
+	logRequest(m.config, "revenium", req, resp.StatusCode, start, nil)
 	defer resp.Body.Close()
 
 	// Read response body for error details
@@ -318,18 +937,118 @@ func (m *MeteringClient) sendMeteringRequest(ctx context.Context, payload map[st
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
 			// Validation error - don't retry
-			return NewValidationError(
+			valErr := NewValidationError(
 				fmt.Sprintf("metering API returned %d: %s", resp.StatusCode, string(body)),
 				nil,
 			)
+			valErr.StatusCode = resp.StatusCode
+			return valErr
+		}
+		meterErr := NewMeteringError("metering API error", fmt.Errorf("status %d: %s", resp.StatusCode, string(body)))
+		meterErr.StatusCode = resp.StatusCode
+		return meterErr
+	}
+
+	if validate := m.config.MeteringResponseValidator; validate != nil {
+		if err := validate(body); err != nil {
+			valErr := NewValidationError("metering response indicates the record was not accepted", err).
+				WithDetails("response", string(body))
+			valErr.StatusCode = resp.StatusCode
+			return valErr
 		}
-		return NewMeteringError("metering API error", fmt.Errorf("status %d: %s", resp.StatusCode, string(body)))
 	}
 
 	Debug("[METERING] Successfully sent metering data")
 	return nil
 }
 
+// truncateForLog returns data as a string of at most limit bytes (0 uses
+// DefaultMeteringMaxBodyLogBytes, < 0 means no limit), cut at a character
+// boundary and suffixed with the number of omitted bytes
+func truncateForLog(data []byte, limit int) string {
+	if limit == 0 {
+		limit = DefaultMeteringMaxBodyLogBytes
+	}
+	if limit < 0 || len(data) <= limit {
+		return string(data)
+	}
+	for limit > 0 && !utf8.RuneStart(data[limit]) {
+		limit--
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", data[:limit], len(data)-limit)
+}
+
+// DefaultMeteringResponseValidator rejects a metering response body that is a
+// JSON object with a non-empty "error" or "errors" field. Empty and non-JSON
+// bodies are accepted.
+func DefaultMeteringResponseValidator(body []byte) error {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+	for _, key := range []string{"error", "errors"} {
+		switch v := response[key].(type) {
+		case nil:
+		case string:
+			if v != "" {
+				return fmt.Errorf("%s: %s", key, v)
+			}
+		case bool:
+			if v {
+				return fmt.Errorf("%s: true", key)
+			}
+		case []interface{}:
+			if len(v) > 0 {
+				return fmt.Errorf("%s: %v", key, v)
+			}
+		default:
+			return fmt.Errorf("%s: %v", key, v)
+		}
+	}
+	return nil
+}
+
+// VerifyCredentials checks the Revenium endpoint without recording usage by
+// sending the API key with a GET to the metering health endpoint. The health
+// endpoint is not documented to validate keys: a 401 or 403 reliably means the
+// key was rejected, but success only proves the endpoint is reachable, and an
+// invalid key may still pass. The first metering record is the authoritative
+// key check; WithMeteringRequired surfaces its failure to the caller.
+func (m *MeteringClient) VerifyCredentials(ctx context.Context) error {
+	if m.config.ReveniumAPIKey == "" {
+		return NewConfigError("Revenium API key not configured", nil)
+	}
+
+	url := m.config.reveniumBaseURL() + "/meter/v2/health"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return NewMeteringError("failed to create verification request", err)
+	}
+	req.Header.Set("x-api-key", m.config.ReveniumAPIKey)
+	req.Header.Set("User-Agent", "revenium-middleware-runway-go/1.0")
+
+	start := m.config.clock().Now()
+	resp, err := m.http.Do(req)
+	if err != nil {
+		logRequest(m.config, "revenium", req, 0, start, err)
+		return NewNetworkError("verification request failed", err)
+	}
+	logRequest(m.config, "revenium", req, resp.StatusCode, start, nil)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return NewAuthError(fmt.Sprintf("Revenium API key rejected (%d)", resp.StatusCode), nil)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return NewMeteringError("metering API error", fmt.Errorf("status %d: %s", resp.StatusCode, string(body)))
+	}
+
+	return nil
+}
+
 // Close closes the metering client
 func (m *MeteringClient) Close() error {
 	// Nothing to clean up for HTTP client
@@ -0,0 +1,384 @@
+package revenium
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestCapturePromptsOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		global   bool
+		override *bool
+		want     bool
+	}{
+		{"global on, call forces on", true, boolPtr(true), true},
+		{"global on, call suppresses", true, boolPtr(false), false},
+		{"global off, call forces on", false, boolPtr(true), true},
+		{"global off, call suppresses", false, boolPtr(false), false},
+		{"global on, no override", true, nil, true},
+		{"global off, no override", false, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runway := newFakeRunway(t)
+			metering := newFakeMetering(t)
+			r := newTestClient(t, runway, metering, WithCapturePrompts(tt.global), WithMeteringRequired(true))
+
+			metadata := &UsageMetadata{CapturePrompts: tt.override}
+			if _, err := r.TextToVideo(context.Background(), &TextToVideoRequest{PromptText: "a red fox at dawn"}, metadata); err != nil {
+				t.Fatalf("TextToVideo() error = %v", err)
+			}
+
+			payloads := metering.received()
+			if len(payloads) != 1 {
+				t.Fatalf("metering records = %d, want 1", len(payloads))
+			}
+			_, captured := payloads[0]["inputMessages"]
+			if captured != tt.want {
+				t.Errorf("inputMessages present = %v, want %v", captured, tt.want)
+			}
+			if tt.want {
+				if _, ok := payloads[0]["outputResponse"]; !ok {
+					t.Error("outputResponse missing with prompt capture on")
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadPerOperation(t *testing.T) {
+	tests := []struct {
+		name          string
+		run           func(ctx context.Context, r *ReveniumRunway) error
+		wantDuration  float64
+		wantRequested float64
+		wantUnknown   bool
+	}{
+		{
+			name: OperationImageToVideo,
+			run: func(ctx context.Context, r *ReveniumRunway) error {
+				_, err := r.ImageToVideo(ctx, &ImageToVideoRequest{PromptImage: "https://example.com/frame.png", Duration: 10}, nil)
+				return err
+			},
+			wantDuration:  10,
+			wantRequested: 10,
+		},
+		{
+			name: OperationVideoToVideo,
+			run: func(ctx context.Context, r *ReveniumRunway) error {
+				_, err := r.VideoToVideo(ctx, &VideoToVideoRequest{PromptVideo: "https://example.com/in.mp4", PromptText: "noir"}, nil)
+				return err
+			},
+			wantDuration:  5,
+			wantRequested: 5,
+		},
+		{
+			name: OperationTextToVideo,
+			run: func(ctx context.Context, r *ReveniumRunway) error {
+				_, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a storm at sea", Duration: 10}, nil)
+				return err
+			},
+			wantDuration:  10,
+			wantRequested: 10,
+		},
+		{
+			name: OperationVideoUpscale,
+			run: func(ctx context.Context, r *ReveniumRunway) error {
+				_, err := r.UpscaleVideo(ctx, &VideoUpscaleRequest{PromptVideo: "https://example.com/in.mp4", SourceDurationSeconds: 12}, nil)
+				return err
+			},
+			wantDuration:  12,
+			wantRequested: 12,
+		},
+		{
+			name: OperationVideoUpscale + " without source duration",
+			run: func(ctx context.Context, r *ReveniumRunway) error {
+				_, err := r.UpscaleVideo(ctx, &VideoUpscaleRequest{PromptVideo: "https://example.com/in.mp4"}, nil)
+				return err
+			},
+			wantDuration:  0,
+			wantRequested: 0,
+			wantUnknown:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runway := newFakeRunway(t)
+			metering := newFakeMetering(t)
+			r := newTestClient(t, runway, metering, WithMeteringRequired(true))
+
+			if err := tt.run(context.Background(), r); err != nil {
+				t.Fatalf("operation error = %v", err)
+			}
+			payloads := metering.received()
+			if len(payloads) != 1 {
+				t.Fatalf("metering records = %d, want 1", len(payloads))
+			}
+			payload := payloads[0]
+
+			if err := validatePayload(payload); err != nil {
+				t.Errorf("payload fails schema validation: %v", err)
+			}
+			if got := payload["durationSeconds"]; got != tt.wantDuration {
+				t.Errorf("durationSeconds = %v, want %v", got, tt.wantDuration)
+			}
+			if got := payload["requestedDurationSeconds"]; got != tt.wantRequested {
+				t.Errorf("requestedDurationSeconds = %v, want %v", got, tt.wantRequested)
+			}
+			if got, _ := payload["durationUnknown"].(bool); got != tt.wantUnknown {
+				t.Errorf("durationUnknown = %v, want %v", got, tt.wantUnknown)
+			}
+			if got := payload["operationType"]; got != "VIDEO" {
+				t.Errorf("operationType = %v, want VIDEO", got)
+			}
+		})
+	}
+}
+
+func TestDefaultDuration(t *testing.T) {
+	tests := []struct {
+		operation string
+		want      float64
+		wantOK    bool
+	}{
+		{"", 5, true},
+		{OperationImageToVideo, 5, true},
+		{OperationVideoToVideo, 5, true},
+		{OperationTextToVideo, 5, true},
+		{OperationVideoUpscale, 0, true},
+		{"image-generation", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := defaultDuration(tt.operation)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("defaultDuration(%q) = %v, %v; want %v, %v", tt.operation, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPayloadOmitsDurationForOperationWithoutOne(t *testing.T) {
+	m := NewMeteringClient(testConfig())
+	result := &VideoGenerationResult{
+		ID:       "task-1",
+		Status:   TaskStatusSucceeded,
+		Metadata: map[string]interface{}{"operationSubtype": "image-generation"},
+	}
+	payload := m.BuildMeteringPayload(result, nil)
+	for _, key := range []string{"durationSeconds", "requestedDurationSeconds"} {
+		if _, ok := payload[key]; ok {
+			t.Errorf("payload has %s for an operation without a duration", key)
+		}
+	}
+}
+
+func TestPerOutputRecordsReplaceCallerParent(t *testing.T) {
+	cfg := testConfig()
+	cfg.MeterPerOutput = true
+	m := NewMeteringClient(cfg)
+	result := &VideoGenerationResult{
+		ID:         "task-1",
+		Status:     TaskStatusSucceeded,
+		OutputURLs: []string{"https://cdn.example.com/1.mp4", "https://cdn.example.com/2.mp4"},
+	}
+
+	payloads := m.preparePayloads(result, &UsageMetadata{ParentTransactionID: "caller-parent", TraceID: "trace"})
+	if len(payloads) != 2 {
+		t.Fatalf("records = %d, want 2", len(payloads))
+	}
+	for i, payload := range payloads {
+		if got := payload["parentTransactionId"]; got != "task-1" {
+			t.Errorf("record %d parentTransactionId = %v, want task-1", i, got)
+		}
+		if got := payload["traceId"]; got != "trace" {
+			t.Errorf("record %d traceId = %v, want trace", i, got)
+		}
+		if got, want := payload["transactionId"], fmt.Sprintf("task-1-%d", i+1); got != want {
+			t.Errorf("record %d transactionId = %v, want %s", i, got, want)
+		}
+	}
+
+	single := m.preparePayloads(&VideoGenerationResult{ID: "task-2", Status: TaskStatusSucceeded, OutputURLs: result.OutputURLs[:1]},
+		&UsageMetadata{ParentTransactionID: "caller-parent"})
+	if got := single[0]["parentTransactionId"]; got != "caller-parent" {
+		t.Errorf("single-output parentTransactionId = %v, want caller-parent", got)
+	}
+}
+
+func TestPerOutputRecordsSplitBilling(t *testing.T) {
+	tests := []struct {
+		name    string
+		basis   BillingBasis
+		outputs int
+	}{
+		{"per second, two outputs", BillingBasisPerSecond, 2},
+		{"per second, three outputs", BillingBasisPerSecond, 3},
+		{"per generation, two outputs", BillingBasisPerGeneration, 2},
+		{"per generation, three outputs", BillingBasisPerGeneration, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MeterPerOutput = true
+			WithBillingBasis("gen4_turbo", tt.basis)(cfg)
+			WithPricingTable(map[string]ModelPricing{
+				"gen4_turbo": {CreditsPerSecond: 5, CreditsPerGeneration: 50, CostPerCredit: 0.01},
+			})(cfg)
+			m := NewMeteringClient(cfg)
+
+			result := &VideoGenerationResult{
+				ID:       "task-1",
+				Status:   TaskStatusSucceeded,
+				Model:    "gen4_turbo",
+				Metadata: map[string]interface{}{"operationSubtype": OperationTextToVideo, "duration": 10},
+			}
+			for i := 0; i < tt.outputs; i++ {
+				result.OutputURLs = append(result.OutputURLs, fmt.Sprintf("https://cdn.example.com/%d.mp4", i))
+			}
+			operation := m.BuildMeteringPayload(result, nil)
+			records := m.preparePayloads(result, nil)
+			if len(records) != tt.outputs {
+				t.Fatalf("records = %d, want %d", len(records), tt.outputs)
+			}
+
+			if want, ok := operation["quantity"].(int); ok {
+				got := 0
+				for _, record := range records {
+					got += record["quantity"].(int)
+				}
+				if got != want {
+					t.Errorf("summed quantity = %d, want %d", got, want)
+				}
+			} else if tt.basis == BillingBasisPerGeneration {
+				t.Fatal("per-generation operation payload has no quantity")
+			}
+			for _, key := range splitBillingFields {
+				want, ok := operation[key].(float64)
+				if !ok {
+					continue
+				}
+				got := 0.0
+				for _, record := range records {
+					got += record[key].(float64)
+				}
+				if math.Abs(got-want) > 1e-9 {
+					t.Errorf("summed %s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+// statusServer answers metering requests with the given status codes in
+// order, then 200, and counts the requests
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(codes) {
+			w.WriteHeader(codes[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryClassifierDecisions(t *testing.T) {
+	retry422 := func(statusCode int, err error) (bool, time.Duration) {
+		if statusCode == http.StatusUnprocessableEntity {
+			return true, time.Millisecond
+		}
+		return false, DeferRetry
+	}
+	stop503 := func(statusCode int, err error) (bool, time.Duration) {
+		if statusCode == http.StatusServiceUnavailable {
+			return false, 0
+		}
+		return false, DeferRetry
+	}
+	stopAll := func(statusCode int, err error) (bool, time.Duration) {
+		return false, 0
+	}
+
+	tests := []struct {
+		name         string
+		classifier   RetryClassifier
+		codes        []int
+		wantErr      bool
+		wantRequests int32
+	}{
+		{"default does not retry 422", nil, []int{422}, true, 1},
+		{"custom retries 422", retry422, []int{422, 422}, false, 3},
+		{"custom defers 500, default retries", retry422, []int{500}, false, 2},
+		{"custom defers 400, default stops", retry422, []int{400}, true, 1},
+		{"custom stops 503 the default retries", stop503, []int{503}, true, 1},
+		{"custom defers 500 while stopping 503", stop503, []int{500}, false, 2},
+		{"plain false stops every retry", stopAll, []int{500}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := statusServer(t, tt.codes...)
+			cfg := testConfig()
+			cfg.ReveniumBaseURL = server.URL
+			cfg.RetryClassifier = tt.classifier
+			cfg.NoMeteringJitter = true
+			m := NewMeteringClient(cfg)
+
+			err := m.SendVideoMetering(context.Background(), &VideoGenerationResult{ID: "task-1", Status: TaskStatusSucceeded}, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendVideoMetering() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		wantAuth bool
+	}{
+		{"reachable", http.StatusOK, false, false},
+		{"key rejected", http.StatusUnauthorized, true, true},
+		{"key forbidden", http.StatusForbidden, true, true},
+		{"server error", http.StatusInternalServerError, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKey, gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotKey, gotPath = r.Header.Get("x-api-key"), r.URL.Path
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			cfg := testConfig()
+			cfg.ReveniumBaseURL = server.URL
+			err := NewMeteringClient(cfg).VerifyCredentials(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsAuthError(err) != tt.wantAuth {
+				t.Errorf("IsAuthError(%v) = %v, want %v", err, IsAuthError(err), tt.wantAuth)
+			}
+			if gotKey != cfg.ReveniumAPIKey || gotPath != "/meter/v2/health" {
+				t.Errorf("request key %q path %q, want %q %q", gotKey, gotPath, cfg.ReveniumAPIKey, "/meter/v2/health")
+			}
+		})
+	}
+}
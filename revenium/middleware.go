@@ -2,8 +2,15 @@ package revenium
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 // ReveniumRunway is the main middleware client that wraps Runway API
@@ -13,7 +20,33 @@ type ReveniumRunway struct {
 	meteringClient *MeteringClient
 	config         *Config
 	mu             sync.RWMutex
-	wg             sync.WaitGroup
+	wg             sync.WaitGroup // Pending metering sends
+	inFlight       atomic.Int64   // Metering goroutines currently running
+	tasks          sync.WaitGroup // Background polling started by Start* methods
+	stop           chan struct{}  // Closed by Close to stop background goroutines
+	closeOnce      sync.Once
+
+	// Payloads awaiting the next scheduled send (WithMeteringSchedule)
+	queueMu sync.Mutex
+	queue   []queuedPayload
+
+	// Detached context for async metering, canceled by FlushContext on timeout
+	meteringCtx    context.Context
+	meteringCancel context.CancelFunc
+
+	// Concurrent task limits, kept across Reconfigure while the limits are unchanged
+	limiter *taskLimiter
+
+	// Duplicate trace ID guard, kept across Reconfigure while the window is unchanged
+	traceGuard *traceGuard
+
+	// Set on the client created by Initialize, which owns the global logger settings
+	ownsLogger bool
+
+	// Cached model list for SupportedModels
+	modelsMu        sync.Mutex
+	models          []ModelInfo
+	modelsFetchedAt time.Time
 }
 
 var (
@@ -45,20 +78,20 @@ func Initialize(opts ...Option) error {
 		Warn("Failed to load configuration from environment: %v", err)
 	}
 
+	// Read keys mounted as files (these take precedence over env vars)
+	if err := cfg.loadKeyFiles(); err != nil {
+		return err
+	}
+
 	// Validate required fields
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
 
 	// Create clients
-	runwayClient := NewRunwayClient(cfg)
-	meteringClient := NewMeteringClient(cfg)
-
-	globalClient = &ReveniumRunway{
-		runwayClient:   runwayClient,
-		meteringClient: meteringClient,
-		config:         cfg,
-	}
+	cfg.applyLoggerSettings()
+	globalClient = newClient(cfg)
+	globalClient.ownsLogger = true
 
 	initialized = true
 	Info("Revenium Runway middleware initialized successfully")
@@ -90,187 +123,691 @@ func NewReveniumRunway(cfg *Config) (*ReveniumRunway, error) {
 		return nil, NewConfigError("config cannot be nil", nil)
 	}
 
+	// Take a private copy so later changes by the caller can't race with metering
+	cfg = cfg.clone()
+
+	// Read keys mounted as files (these take precedence over the key fields)
+	if err := cfg.loadKeyFiles(); err != nil {
+		return nil, err
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
-	runwayClient := NewRunwayClient(cfg)
-	meteringClient := NewMeteringClient(cfg)
+	return newClient(cfg), nil
+}
 
-	return &ReveniumRunway{
-		runwayClient:   runwayClient,
-		meteringClient: meteringClient,
+// newClient creates the clients for a validated configuration and starts any
+// background work it requires
+func newClient(cfg *Config) *ReveniumRunway {
+	r := &ReveniumRunway{
+		runwayClient:   NewRunwayClient(cfg),
+		meteringClient: NewMeteringClient(cfg),
 		config:         cfg,
-	}, nil
+		limiter:        newTaskLimiter(cfg),
+		traceGuard:     newTraceGuard(cfg),
+		stop:           make(chan struct{}),
+	}
+	r.meteringCtx, r.meteringCancel = context.WithCancel(context.Background())
+	r.startKeyFileRefresh()
+	r.startMeteringSchedule()
+	return r
 }
 
-// GetConfig returns the configuration
+// GetConfig returns a copy of the current configuration.
+// Modifying the returned value has no effect; use Reconfigure instead.
 func (r *ReveniumRunway) GetConfig() *Config {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.config
+	return r.config.clone()
 }
 
-// ImageToVideo generates a video from an image with automatic metering
-func (r *ReveniumRunway) ImageToVideo(ctx context.Context, req *ImageToVideoRequest, metadata *UsageMetadata) (*VideoGenerationResult, error) {
-	startTime := time.Now()
+// Reconfigure applies options to a copy of the current configuration and, if it
+// validates, swaps it in. Operations already in flight (including their metering)
+// keep using the configuration they started with.
+func (r *ReveniumRunway) Reconfigure(opts ...Option) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	// Set default model if not specified
-	if req.Model == "" {
-		req.Model = "gen3a_turbo"
+	cfg := r.config.clone()
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
-	// Create task
-	Debug("Creating image-to-video task with model: %s", req.Model)
-	taskResp, err := r.runwayClient.CreateImageToVideo(ctx, req)
-	if err != nil {
-		return nil, err
+	if err := cfg.Validate(); err != nil {
+		return err
 	}
 
-	// Wait for task completion
-	Info("Waiting for task %s to complete...", taskResp.ID)
-	statusResp, err := r.runwayClient.WaitForTaskCompletion(ctx, taskResp.ID, DefaultPollingConfig())
-	if err != nil {
-		return nil, err
+	r.swapConfig(cfg)
+	if r.ownsLogger {
+		cfg.applyLoggerSettings()
 	}
 
-	// Build result
-	duration := time.Since(startTime)
-	result := &VideoGenerationResult{
-		ID:         taskResp.ID,
-		Status:     statusResp.Status,
-		OutputURLs: statusResp.Output,
-		Duration:   duration,
-		Model:      req.Model,
-		Metadata:   make(map[string]interface{}),
+	Debug("Configuration updated")
+	return nil
+}
+
+// swapConfig installs a validated configuration and new clients built from it.
+// The caller must hold r.mu.
+func (r *ReveniumRunway) swapConfig(cfg *Config) {
+	if !sameConcurrencyLimits(r.config, cfg) {
+		r.limiter = newTaskLimiter(cfg)
+	}
+	if r.config.TraceDedupWindow != cfg.TraceDedupWindow {
+		r.traceGuard = newTraceGuard(cfg)
 	}
+	r.config = cfg
+	r.runwayClient = NewRunwayClient(cfg)
+	r.meteringClient = NewMeteringClient(cfg)
+}
 
-	// Store requested duration for metering (per-second billing)
-	if req.Duration > 0 {
-		result.Metadata["requestedDuration"] = req.Duration
-	} else {
-		result.Metadata["requestedDuration"] = 5 // Runway default
+// startKeyFileRefresh periodically re-reads API key files so rotated secrets
+// are picked up without a restart. It runs until Close.
+func (r *ReveniumRunway) startKeyFileRefresh() {
+	interval := r.config.APIKeyFileRefreshInterval
+	if interval <= 0 || (r.config.RunwayAPIKeyFile == "" && r.config.ReveniumAPIKeyFile == "") {
+		return
 	}
 
-	// Store prompt for capture if enabled (used by metering client)
-	if r.config.CapturePrompts && req.PromptText != "" {
-		result.Metadata["_capturedPrompt"] = req.PromptText
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.refreshKeyFiles()
+			}
+		}
+	}()
+}
+
+// refreshKeyFiles re-reads the API key files and swaps in new clients when a
+// key has changed. Unreadable files and invalid keys keep the current keys.
+func (r *ReveniumRunway) refreshKeyFiles() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg := r.config.clone()
+	if err := cfg.loadKeyFiles(); err != nil {
+		Warn("Failed to reload API key files: %v", err)
+		return
 	}
 
-	// Copy error information if failed
-	if statusResp.Error != nil {
-		result.Error = statusResp.Error
+	if cfg.RunwayAPIKey == r.config.RunwayAPIKey && cfg.ReveniumAPIKey == r.config.ReveniumAPIKey {
+		return
 	}
-	if statusResp.FailureCode != nil {
-		result.FailureCode = statusResp.FailureCode
+
+	if err := cfg.Validate(); err != nil {
+		Warn("Ignoring rotated API key: %v", err)
+		return
 	}
 
-	// Send metering asynchronously (fire-and-forget)
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		r.sendMetering(context.Background(), result, metadata)
-	}()
+	r.swapConfig(cfg)
+	Info("Reloaded rotated API keys from file")
+}
 
-	return result, nil
+// clientSnapshot is the configuration and clients captured at the start of an
+// operation. Configs are never mutated once handed to clients, so reading them
+// through a snapshot is safe while Reconfigure runs concurrently.
+type clientSnapshot struct {
+	config     *Config
+	runway     *RunwayClient
+	metering   *MeteringClient
+	limiter    *taskLimiter
+	traceGuard *traceGuard
+}
+
+// snapshot returns the current configuration and clients under the read lock
+func (r *ReveniumRunway) snapshot() *clientSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return &clientSnapshot{
+		config:     r.config,
+		runway:     r.runwayClient,
+		metering:   r.meteringClient,
+		limiter:    r.limiter,
+		traceGuard: r.traceGuard,
+	}
+}
+
+// ImageToVideo generates a video from an image with automatic metering.
+// With WithMeteringRequired enabled, a metering failure returns both the result
+// and a metering error; see WithMeteringRequired for the contract.
+func (r *ReveniumRunway) ImageToVideo(ctx context.Context, req *ImageToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoGenerationResult, error) {
+	return r.runOperation(ctx, imageToVideoOperation(req), metadata, opts)
+}
+
+// StartImageToVideo creates an image-to-video task and returns a handle while
+// polling and metering continue in the background
+func (r *ReveniumRunway) StartImageToVideo(ctx context.Context, req *ImageToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*TaskHandle, error) {
+	return r.startOperationAsync(ctx, imageToVideoOperation(req), metadata, opts)
+}
+
+// SubmitImageToVideo creates an image-to-video task and returns as soon as it
+// exists. Polling and metering happen in the caller's goroutine when
+// VideoTask.Wait is called.
+func (r *ReveniumRunway) SubmitImageToVideo(ctx context.Context, req *ImageToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoTask, error) {
+	return r.submitOperation(ctx, imageToVideoOperation(req), metadata, opts)
 }
 
 // VideoToVideo transforms a video with automatic metering
-func (r *ReveniumRunway) VideoToVideo(ctx context.Context, req *VideoToVideoRequest, metadata *UsageMetadata) (*VideoGenerationResult, error) {
-	startTime := time.Now()
+func (r *ReveniumRunway) VideoToVideo(ctx context.Context, req *VideoToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoGenerationResult, error) {
+	return r.runOperation(ctx, videoToVideoOperation(req), metadata, opts)
+}
+
+// StartVideoToVideo creates a video-to-video task and returns a handle while
+// polling and metering continue in the background
+func (r *ReveniumRunway) StartVideoToVideo(ctx context.Context, req *VideoToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*TaskHandle, error) {
+	return r.startOperationAsync(ctx, videoToVideoOperation(req), metadata, opts)
+}
+
+// TextToVideo generates a video from a text prompt with automatic metering
+func (r *ReveniumRunway) TextToVideo(ctx context.Context, req *TextToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoGenerationResult, error) {
+	return r.runOperation(ctx, textToVideoOperation(req), metadata, opts)
+}
+
+// StartTextToVideo creates a text-to-video task and returns a handle while
+// polling and metering continue in the background
+func (r *ReveniumRunway) StartTextToVideo(ctx context.Context, req *TextToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*TaskHandle, error) {
+	return r.startOperationAsync(ctx, textToVideoOperation(req), metadata, opts)
+}
+
+// SubmitVideoToVideo creates a video-to-video task and returns as soon as it
+// exists; see SubmitImageToVideo
+func (r *ReveniumRunway) SubmitVideoToVideo(ctx context.Context, req *VideoToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoTask, error) {
+	return r.submitOperation(ctx, videoToVideoOperation(req), metadata, opts)
+}
+
+// SubmitTextToVideo creates a text-to-video task and returns as soon as it
+// exists; see SubmitImageToVideo
+func (r *ReveniumRunway) SubmitTextToVideo(ctx context.Context, req *TextToVideoRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoTask, error) {
+	return r.submitOperation(ctx, textToVideoOperation(req), metadata, opts)
+}
 
+// UpscaleVideo upscales a video with automatic metering
+func (r *ReveniumRunway) UpscaleVideo(ctx context.Context, req *VideoUpscaleRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoGenerationResult, error) {
+	return r.runOperation(ctx, upscaleVideoOperation(req), metadata, opts)
+}
+
+// StartUpscaleVideo creates a video upscale task and returns a handle while
+// polling and metering continue in the background
+func (r *ReveniumRunway) StartUpscaleVideo(ctx context.Context, req *VideoUpscaleRequest, metadata *UsageMetadata, opts ...CallOption) (*TaskHandle, error) {
+	return r.startOperationAsync(ctx, upscaleVideoOperation(req), metadata, opts)
+}
+
+// imageToVideoOperation builds the operation for an image-to-video request
+func imageToVideoOperation(req *ImageToVideoRequest) *operation {
 	// Set default model if not specified
 	if req.Model == "" {
-		req.Model = "gen3a_turbo"
+		req.Model = DefaultVideoModel
 	}
 
-	// Create task
-	Debug("Creating video-to-video task with model: %s", req.Model)
-	taskResp, err := r.runwayClient.CreateVideoToVideo(ctx, req)
+	return &operation{
+		name:    OperationImageToVideo,
+		model:   req.Model,
+		request: req,
+		create: func(ctx context.Context, client *RunwayClient) (*TaskResponse, error) {
+			return client.CreateImageToVideo(ctx, req)
+		},
+		prepare: func(result *VideoGenerationResult, cfg *Config, status *TaskStatusResponse) {
+			addGenerationMetadata(result, req.Duration, req.PromptText, negativePrompt(req.ExtraParams))
+			addRequestParams(result, req.Ratio, req.Seed, req.Watermark)
+			addResolution(result, req.Ratio, status)
+		},
+	}
+}
+
+// videoToVideoOperation builds the operation for a video-to-video request
+func videoToVideoOperation(req *VideoToVideoRequest) *operation {
+	// Set default model if not specified
+	if req.Model == "" {
+		req.Model = DefaultVideoModel
+	}
+
+	return &operation{
+		name:    OperationVideoToVideo,
+		model:   req.Model,
+		request: req,
+		create: func(ctx context.Context, client *RunwayClient) (*TaskResponse, error) {
+			return client.CreateVideoToVideo(ctx, req)
+		},
+		prepare: func(result *VideoGenerationResult, cfg *Config, status *TaskStatusResponse) {
+			addGenerationMetadata(result, req.Duration, req.PromptText, negativePrompt(req.ExtraParams))
+			addRequestParams(result, "", req.Seed, req.Watermark)
+			addResolution(result, "", status)
+		},
+	}
+}
+
+// textToVideoOperation builds the operation for a text-to-video request
+func textToVideoOperation(req *TextToVideoRequest) *operation {
+	// Set default model if not specified
+	if req.Model == "" {
+		req.Model = DefaultVideoModel
+	}
+
+	return &operation{
+		name:    OperationTextToVideo,
+		model:   req.Model,
+		request: req,
+		create: func(ctx context.Context, client *RunwayClient) (*TaskResponse, error) {
+			return client.CreateTextToVideo(ctx, req)
+		},
+		prepare: func(result *VideoGenerationResult, cfg *Config, status *TaskStatusResponse) {
+			addGenerationMetadata(result, req.Duration, req.PromptText, "")
+			addRequestParams(result, req.Ratio, req.Seed, req.Watermark)
+			addResolution(result, req.Ratio, status)
+		},
+	}
+}
+
+// upscaleVideoOperation builds the operation for a video upscale request
+func upscaleVideoOperation(req *VideoUpscaleRequest) *operation {
+	// Set default model if not specified
+	if req.Model == "" {
+		req.Model = DefaultUpscaleModel
+	}
+
+	return &operation{
+		name:    OperationVideoUpscale,
+		model:   req.Model,
+		request: req,
+		create: func(ctx context.Context, client *RunwayClient) (*TaskResponse, error) {
+			return client.CreateVideoUpscale(ctx, req)
+		},
+		prepare: func(result *VideoGenerationResult, cfg *Config, status *TaskStatusResponse) {
+			addUpscaleMetadata(result, req, status)
+			addResolution(result, "", status)
+		},
+	}
+}
+
+// SubmitUpscaleVideo creates a video upscale task and returns as soon as it
+// exists; see SubmitImageToVideo
+func (r *ReveniumRunway) SubmitUpscaleVideo(ctx context.Context, req *VideoUpscaleRequest, metadata *UsageMetadata, opts ...CallOption) (*VideoTask, error) {
+	return r.submitOperation(ctx, upscaleVideoOperation(req), metadata, opts)
+}
+
+// operation describes a single Runway generation call handled by runOperation
+type operation struct {
+	name    string                                                                       // Operation name used in logs
+	request interface{}                                                                  // Original request, passed to hooks
+	model   string                                                                       // Model the task was created with
+	create  func(ctx context.Context, client *RunwayClient) (*TaskResponse, error)       // Creates the Runway task
+	prepare func(result *VideoGenerationResult, cfg *Config, status *TaskStatusResponse) // Adds request-specific result metadata (optional)
+}
+
+// Operation stages, reported in deadline errors
+const (
+	stageCreate   = "create"
+	stagePoll     = "poll"
+	stageMetering = "metering"
+)
+
+// pendingOperation is an operation whose Runway task has been created
+type pendingOperation struct {
+	op            *operation
+	call          *callOptions
+	snap          *clientSnapshot
+	transfer      *transferCounter
+	correlationID string
+	traceID       string // Sent with every Runway request of the operation
+	capture       bool   // Prompts are captured for this operation
+	release       func() // Releases the operation's concurrency slot
+	taskID        string
+	stage         string
+	startTime     time.Time
+	createdAt     time.Time
+}
+
+// logPrefix returns the log line prefix for the operation
+func (p *pendingOperation) logPrefix() string {
+	return logPrefix(p.correlationID)
+}
+
+// pollingConfig returns the per-call polling configuration, or the client's
+// configuration for the operation's model
+func (p *pendingOperation) pollingConfig() *PollingConfig {
+	if p.call.polling != nil {
+		return p.call.polling
+	}
+	return p.snap.config.pollingConfigFor(p.op.model)
+}
+
+// runOperation creates a task, waits for it to complete, builds the result and
+// enqueues metering. It is shared by all generation methods.
+func (r *ReveniumRunway) runOperation(ctx context.Context, op *operation, metadata *UsageMetadata, opts []CallOption) (_ *VideoGenerationResult, err error) {
+	defer r.recoverOperation(ctx, op.name, &err)
+	call := r.callOptions(ctx, opts)
+	metadata = mergeMetadata(r.snapshot().config.DefaultMetadata, metadata)
+	ctx, cancel := call.withDeadline(ctx)
+	defer cancel()
+
+	for resubmits := 0; ; resubmits++ {
+		pending, err := r.startOperation(ctx, op, metadata, call)
+		if err != nil {
+			return nil, call.deadlineError(ctx, stageCreate, err)
+		}
+
+		result, err := r.finishOperation(ctx, pending, metadata)
+		pending.release()
+		maxResubmits := pending.pollingConfig().maxResubmits()
+		if IsRetryableTaskFailure(err) && resubmits < maxResubmits {
+			Warn("%sTask %s failed with a retryable error; resubmitting (%d of %d)", pending.logPrefix(), pending.taskID, resubmits+1, maxResubmits)
+			if metadata != nil {
+				pending.snap.traceGuard.forget(metadata.TraceID)
+			}
+			metadata = resubmitMetadata(metadata, pending.taskID)
+			if err := resubmitClientToken(op.request, pending.taskID, pending.snap.config.RunwayIdempotency); err != nil {
+				return result, err
+			}
+			continue
+		}
+		return result, call.deadlineError(ctx, pending.stage, err)
+	}
+}
+
+// resubmitMetadata returns a copy of the metadata for resubmitting a failed
+// task: RetryNumber is incremented and OriginalTransactionID points to the
+// first attempt
+func resubmitMetadata(metadata *UsageMetadata, failedTaskID string) *UsageMetadata {
+	next := &UsageMetadata{}
+	if metadata != nil {
+		*next = *metadata
+	}
+	retry := 1
+	if next.RetryNumber != nil {
+		retry = *next.RetryNumber + 1
+	}
+	next.RetryNumber = &retry
+	if next.OriginalTransactionID == "" {
+		next.OriginalTransactionID = failedTaskID
+	}
+	return next
+}
+
+// resubmitClientToken replaces the idempotency token of a request so a
+// resubmission is not deduplicated against the failed task. With derive set,
+// the new token is derived from the request and the failed task ID; otherwise
+// it is cleared.
+func resubmitClientToken(req interface{}, failedTaskID string, derive bool) error {
+	var token string
+	if derive {
+		var err error
+		if token, err = idempotencyKey("resubmit:"+failedTaskID, req); err != nil {
+			return err
+		}
+	}
+
+	switch r := req.(type) {
+	case *ImageToVideoRequest:
+		r.ClientToken = token
+	case *VideoToVideoRequest:
+		r.ClientToken = token
+	case *TextToVideoRequest:
+		r.ClientToken = token
+	case *VideoUpscaleRequest:
+		r.ClientToken = token
+	}
+	return nil
+}
+
+// callOptions applies the per-call options, falling back to the configured
+// default operation timeout when neither the call nor ctx sets a deadline
+func (r *ReveniumRunway) callOptions(ctx context.Context, opts []CallOption) *callOptions {
+	call := newCallOptions(opts)
+	if call.deadline > 0 {
+		return call
+	}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		call.deadline = r.snapshot().config.DefaultOperationTimeout
+	}
+	return call
+}
+
+// startOperation captures the client snapshot and creates the Runway task
+func (r *ReveniumRunway) startOperation(ctx context.Context, op *operation, metadata *UsageMetadata, call *callOptions) (*pendingOperation, error) {
+	snap := r.snapshot()
+	pending := &pendingOperation{
+		op:        op,
+		call:      call,
+		stage:     stageCreate,
+		startTime: snap.config.clock().Now(),
+		snap:      snap,
+		capture:   capturePrompts(snap.config, metadata),
+	}
+	ctx, pending.transfer = withTransferCounter(ctx)
+	pending.traceID = traceIDFrom(ctx)
+	if metadata != nil && metadata.TraceID != "" {
+		pending.traceID = metadata.TraceID
+	}
+	ctx = ContextWithTraceID(ctx, pending.traceID)
+	if generate := snap.config.CorrelationIDGenerator; generate != nil {
+		pending.correlationID = generate()
+		ctx = withCorrelationID(ctx, pending.correlationID)
+	}
+
+	// Reject unsupported features before spending a round-trip
+	if snap.config.ModelCapabilityCheck {
+		if err := ValidateRequest(op.request); err != nil {
+			return nil, err
+		}
+	}
+
+	// Enforce caller policy before creating the task
+	if err := snap.config.checkCustomKeys(metadata); err != nil {
+		return nil, err
+	}
+	if validate := snap.config.RequestValidator; validate != nil {
+		if err := validate(op.request, metadata); err != nil {
+			return nil, NewValidationError("request rejected by validator", err)
+		}
+	}
+
+	// Reject repeat submissions of the same trace
+	var traceID string
+	if metadata != nil {
+		traceID = metadata.TraceID
+	}
+	if !snap.traceGuard.admit(traceID) {
+		Warn("%sRejecting duplicate submission for trace %s", pending.logPrefix(), traceID)
+		return nil, NewDuplicateError("duplicate submission for trace ID within dedup window", traceID)
+	}
+
+	// Wait for a concurrency slot; it is held until polling finishes
+	release, err := snap.limiter.acquire(ctx, op.model)
 	if err != nil {
+		snap.traceGuard.forget(traceID)
 		return nil, err
 	}
+	pending.release = sync.OnceFunc(release) // A VideoTask may finish polling more than once
 
-	// Wait for task completion
-	Info("Waiting for task %s to complete...", taskResp.ID)
-	statusResp, err := r.runwayClient.WaitForTaskCompletion(ctx, taskResp.ID, DefaultPollingConfig())
+	// Create task
+	Debug("%sCreating %s task with model: %s", pending.logPrefix(), op.name, op.model)
+	taskResp, err := op.create(ctx, pending.snap.runway)
 	if err != nil {
+		release()
+		snap.traceGuard.forget(traceID)
 		return nil, err
 	}
+	pending.taskID = taskResp.ID
+	pending.createdAt = pending.snap.config.clock().Now()
 
-	// Build result
-	duration := time.Since(startTime)
-	result := &VideoGenerationResult{
-		ID:         taskResp.ID,
-		Status:     statusResp.Status,
-		OutputURLs: statusResp.Output,
-		Duration:   duration,
-		Model:      req.Model,
-		Metadata:   make(map[string]interface{}),
+	// Let the caller persist the task ID before the long poll starts
+	if hook := pending.snap.config.OnTaskCreated; hook != nil {
+		runTaskCreatedHook(ctx, hook, taskResp.ID, op.request, metadata, pending.logPrefix())
 	}
 
-	// Store requested duration for metering (per-second billing)
-	if req.Duration > 0 {
-		result.Metadata["requestedDuration"] = req.Duration
-	} else {
-		result.Metadata["requestedDuration"] = 5 // Runway default
+	if pending.snap.config.LifecycleMetering {
+		r.meterStart(pending, metadata)
 	}
 
-	// Store prompt for capture if enabled (used by metering client)
-	if r.config.CapturePrompts && req.PromptText != "" {
-		result.Metadata["_capturedPrompt"] = req.PromptText
+	return pending, nil
+}
+
+// meterStart sends the lifecycle start record for a created task. It never
+// blocks the operation: the record is validated inline, queued, or sent in the
+// background depending on the metering mode.
+func (r *ReveniumRunway) meterStart(p *pendingOperation, metadata *UsageMetadata) {
+	result := buildResult(p, &TaskStatusResponse{ID: p.taskID, Status: TaskStatusPending})
+	payload := p.snap.metering.prepareStartPayload(result, metadata)
+	baseURL := meteringBaseURL(metadata)
+
+	switch {
+	case p.snap.config.InlineMetering:
+		if err := validatePayload(payload); err != nil {
+			Error("%sInvalid start metering payload for task %s: %v", p.logPrefix(), p.taskID, err)
+			return
+		}
+		Debug("%s[METERING] Inline start payload (not sent): %v", p.logPrefix(), payload)
+	case p.snap.config.MeteringSchedule > 0:
+		r.enqueuePayload(p.snap.metering, payload, baseURL)
+	default:
+		meteringCtx := r.asyncMeteringContext()
+		r.goMetering(func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					Error("%sStart metering goroutine panic: %v", p.logPrefix(), rec)
+				}
+			}()
+			if err := p.snap.metering.sendWithRetry(meteringCtx, payload, baseURL); err != nil {
+				Error("%sFailed to send start metering for task %s: %v", p.logPrefix(), p.taskID, err)
+			}
+		})
 	}
+}
 
-	// Copy error information if failed
-	if statusResp.Error != nil {
-		result.Error = statusResp.Error
+// recoverOperation converts a panic in an operation method into an
+// InternalError stored in *errp, with the panic value and stack in its
+// details, and reports it to the OnPanic hook. A faulty callback then fails the
+// call instead of unwinding into the caller's goroutine. It must be deferred
+// directly by the operation method.
+func (r *ReveniumRunway) recoverOperation(ctx context.Context, name string, errp *error) {
+	rec := recover()
+	if rec == nil {
+		return
 	}
-	if statusResp.FailureCode != nil {
-		result.FailureCode = statusResp.FailureCode
+	stack := debug.Stack()
+	Error("Recovered panic in %s operation: %v\n%s", name, rec, stack)
+
+	cause, _ := rec.(error)
+	*errp = NewInternalError(fmt.Sprintf("panic in %s operation: %v", name, rec), cause).
+		WithDetails("panic", rec).
+		WithDetails("stack", string(stack))
+	if hook := r.snapshot().config.OnPanic; hook != nil {
+		runPanicHook(ctx, hook, rec, stack)
 	}
+}
 
-	// Send metering asynchronously (fire-and-forget)
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		r.sendMetering(context.Background(), result, metadata)
+// runPanicHook invokes the OnPanic hook, recovering from a panic in the hook
+// itself
+func runPanicHook(ctx context.Context, hook PanicHook, rec interface{}, stack []byte) {
+	defer func() {
+		if hookRec := recover(); hookRec != nil {
+			Error("OnPanic hook panic: %v", hookRec)
+		}
 	}()
+	hook(ctx, rec, stack)
+}
 
-	return result, nil
+// runTaskCreatedHook invokes the OnTaskCreated hook, recovering from panics so
+// a faulty hook cannot abandon a task that has already been created
+func runTaskCreatedHook(ctx context.Context, hook TaskCreatedHook, taskID string, req interface{}, metadata *UsageMetadata, prefix string) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			Error("%sOnTaskCreated hook panic for task %s: %v", prefix, taskID, rec)
+		}
+	}()
+	hook(ctx, taskID, req, metadata)
 }
 
-// UpscaleVideo upscales a video with automatic metering
-func (r *ReveniumRunway) UpscaleVideo(ctx context.Context, req *VideoUpscaleRequest, metadata *UsageMetadata) (*VideoGenerationResult, error) {
-	startTime := time.Now()
+// finishOperation waits for a created task to complete, then builds the result
+// and sends metering
+func (r *ReveniumRunway) finishOperation(ctx context.Context, p *pendingOperation, metadata *UsageMetadata) (*VideoGenerationResult, error) {
+	ctx = context.WithValue(ctx, transferCounterKey{}, p.transfer)
+	ctx = withCorrelationID(ctx, p.correlationID)
+	ctx = ContextWithTraceID(ctx, p.traceID)
 
-	// Set default model if not specified
-	if req.Model == "" {
-		req.Model = "upscale"
+	// Wait for task completion
+	p.stage = stagePoll
+	polled := false
+	defer func() {
+		if !polled {
+			p.release() // Polling panicked
+		}
+	}()
+	Info("%sWaiting for task %s to complete...", p.logPrefix(), p.taskID)
+	statusResp, stats, err := p.snap.runway.waitForTask(ctx, p.taskID, p.pollingConfig())
+	polled = true
+	if err != nil && ctx.Err() != nil {
+		// Keep the slot: VideoTask.Wait resumes polling later, and the other
+		// callers release it themselves
+		return nil, err
 	}
-
-	// Create task
-	Debug("Creating video upscale task with model: %s", req.Model)
-	taskResp, err := r.runwayClient.CreateVideoUpscale(ctx, req)
-	if err != nil {
+	p.release()
+	canceled := statusResp != nil && statusResp.Status == TaskStatusCanceled
+	if err != nil && !IsRetryableTaskFailure(err) && !canceled {
 		return nil, err
 	}
 
-	// Wait for task completion
-	Info("Waiting for task %s to complete...", taskResp.ID)
-	statusResp, err := r.runwayClient.WaitForTaskCompletion(ctx, taskResp.ID, DefaultPollingConfig())
+	p.stage = stageMetering
+	result := buildResult(p, statusResp)
+	result.PollCount, result.LastProgress = stats.Polls, stats.LastProgress
+	if canceled {
+		// Canceled elsewhere, e.g. with CancelTask; metered with stopReason CANCELLED
+		result.Metadata["cancellationReason"] = "task canceled"
+	}
 	if err != nil {
-		return nil, err
+		// Meter the failed or canceled attempt so it is not lost, and so a
+		// resubmission does not hide it
+		if _, meterErr := r.meterResult(ctx, p, result, metadata, stats); meterErr != nil {
+			Warn("%sFailed to meter failed task %s: %v", p.logPrefix(), result.ID, meterErr)
+		}
+		return result, err
 	}
+	probeOutputDuration(ctx, p.snap.config, result)
+	if dir := p.snap.config.OutputDownloadDir; dir != "" && result.Status == TaskStatusSucceeded {
+		if err := r.DownloadOutputs(ctx, result, dir); err != nil {
+			Warn("%sFailed to download outputs for task %s: %v", p.logPrefix(), result.ID, err)
+		}
+	}
+	return r.meterResult(ctx, p, result, metadata, stats)
+}
 
-	// Build result
-	duration := time.Since(startTime)
+// buildResult converts the final task status into a VideoGenerationResult
+func buildResult(p *pendingOperation, statusResp *TaskStatusResponse) *VideoGenerationResult {
+	duration := p.snap.config.clock().Since(p.startTime)
 	result := &VideoGenerationResult{
-		ID:         taskResp.ID,
-		Status:     statusResp.Status,
-		OutputURLs: statusResp.Output,
-		Duration:   duration,
-		Model:      req.Model,
+		ID:            p.taskID,
+		Status:        statusResp.Status,
+		OutputURLs:    statusResp.Output,
+		Duration:      duration,
+		Model:         p.op.model,
+		RequestBytes:  atomic.LoadInt64(&p.transfer.requestBytes),
+		ResponseBytes: atomic.LoadInt64(&p.transfer.responseBytes),
+		RawStatus:     statusResp.Raw,
+		CorrelationID: p.correlationID,
+	}
+	if p.op.prepare != nil {
+		p.op.prepare(result, p.snap.config, statusResp)
+	}
+	addFrameInfo(result, statusResp)
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	// operationType is always VIDEO; tag which generation produced it
+	result.Metadata["operationSubtype"] = p.op.name
+	if prompt, _ := result.Metadata["_capturedPrompt"].(string); prompt != "" && p.snap.config.PromptMetrics {
+		addPromptMetrics(result, prompt)
+	}
+	if !p.capture {
+		delete(result.Metadata, "_capturedPrompt")
+		delete(result.Metadata, "_capturedNegativePrompt")
 	}
 
 	// Copy error information if failed
@@ -281,40 +818,738 @@ func (r *ReveniumRunway) UpscaleVideo(ctx context.Context, req *VideoUpscaleRequ
 		result.FailureCode = statusResp.FailureCode
 	}
 
+	return result
+}
+
+// meterResult sends metering for a finished operation, synchronously when
+// metering is required and fire-and-forget otherwise
+func (r *ReveniumRunway) meterResult(ctx context.Context, p *pendingOperation, result *VideoGenerationResult, metadata *UsageMetadata, stats *pollStats) (*VideoGenerationResult, error) {
+	// Inline metering: build and validate the payloads here, never send them
+	if p.snap.config.InlineMetering {
+		logLifecycle(p.op, result, p.startTime, p.createdAt, stats, false)
+		for _, payload := range p.snap.metering.preparePayloads(result, metadata) {
+			if err := validatePayload(payload); err != nil {
+				Error("%sInvalid metering payload for task %s: %v", p.logPrefix(), result.ID, err)
+				return result, err
+			}
+			Debug("%s[METERING] Inline metering payload (not sent): %v", p.logPrefix(), payload)
+		}
+		return result, nil
+	}
+
+	// Metering is required: send synchronously and surface failures to the caller
+	if p.snap.config.MeteringRequired {
+		err := p.snap.metering.SendVideoMetering(ctx, result, metadata)
+		logLifecycle(p.op, result, p.startTime, p.createdAt, stats, false)
+		if err != nil {
+			Error("%sRequired metering failed for task %s: %v", p.logPrefix(), result.ID, err)
+			if !IsMeteringError(err) {
+				err = NewMeteringError("required metering failed", err)
+			}
+			return result, err
+		}
+		return result, nil
+	}
+
+	// Scheduled metering: build the payload now (fixing its timestamps) and
+	// send it with the next scheduled flush
+	if p.snap.config.MeteringSchedule > 0 {
+		for _, payload := range p.snap.metering.preparePayloads(result, metadata) {
+			r.enqueuePayload(p.snap.metering, payload, meteringBaseURL(metadata))
+		}
+		logLifecycle(p.op, result, p.startTime, p.createdAt, stats, true)
+		return result, nil
+	}
+
 	// Send metering asynchronously (fire-and-forget)
-	r.wg.Add(1)
+	meteringCtx := r.asyncMeteringContext()
+	r.goMetering(func() {
+		r.sendMetering(meteringCtx, p.snap.metering, result, metadata)
+	})
+
+	logLifecycle(p.op, result, p.startTime, p.createdAt, stats, true)
+
+	return result, nil
+}
+
+// startOperationAsync creates the task, then polls and meters in the background
+func (r *ReveniumRunway) startOperationAsync(ctx context.Context, op *operation, metadata *UsageMetadata, opts []CallOption) (_ *TaskHandle, err error) {
+	defer r.recoverOperation(ctx, op.name, &err)
+	call := r.callOptions(ctx, opts)
+	metadata = mergeMetadata(r.snapshot().config.DefaultMetadata, metadata)
+	ctx, cancelDeadline := call.withDeadline(ctx)
+
+	pending, err := r.startOperation(ctx, op, metadata, call)
+	if err != nil {
+		cancelDeadline()
+		return nil, call.deadlineError(ctx, stageCreate, err)
+	}
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	handle := &TaskHandle{
+		TaskID: pending.taskID,
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	r.tasks.Add(1)
 	go func() {
-		defer r.wg.Done()
-		r.sendMetering(context.Background(), result, metadata)
+		defer r.tasks.Done()
+		defer close(handle.done)
+		defer cancelDeadline()
+		defer cancel()
+		defer r.recoverOperation(ctx, op.name, &handle.err)
+
+		result, err := r.finishOperation(pollCtx, pending, metadata)
+		pending.release()
+		if handle.canceled.Load() && errors.Is(err, context.Canceled) {
+			result, err = r.cancelOperation(pending, metadata, handle.cancelTask.Load())
+		} else {
+			err = call.deadlineError(ctx, pending.stage, err)
+		}
+		handle.result, handle.err = result, err
 	}()
 
+	return handle, nil
+}
+
+// submitOperation creates the task and returns a VideoTask that polls and
+// meters it when waited on
+func (r *ReveniumRunway) submitOperation(ctx context.Context, op *operation, metadata *UsageMetadata, opts []CallOption) (_ *VideoTask, err error) {
+	defer r.recoverOperation(ctx, op.name, &err)
+	call := r.callOptions(ctx, opts)
+	metadata = mergeMetadata(r.snapshot().config.DefaultMetadata, metadata)
+	createCtx, cancel := call.withDeadline(ctx)
+	defer cancel()
+
+	pending, err := r.startOperation(createCtx, op, metadata, call)
+	if err != nil {
+		return nil, call.deadlineError(createCtx, stageCreate, err)
+	}
+	return &VideoTask{TaskID: pending.taskID, r: r, pending: pending, metadata: metadata}, nil
+}
+
+// VideoTask is a created Runway task whose polling and metering run when Wait
+// is called, letting callers submit many tasks and await them with their own
+// concurrency control. The task holds its concurrency slot (see
+// WithMaxConcurrentTasks) until a Wait sees it finish; a Wait interrupted by
+// its context keeps the slot for the next Wait. Every VideoTask should be
+// waited on until it finishes.
+type VideoTask struct {
+	TaskID string // Runway task ID
+
+	r        *ReveniumRunway
+	pending  *pendingOperation
+	metadata *UsageMetadata
+
+	mu     sync.Mutex
+	done   bool
+	result *VideoGenerationResult
+	err    error
+}
+
+// Wait polls the task until it reaches a terminal state, then meters it and
+// returns the result. The operation deadline from WithOperationDeadline applies
+// to Wait separately from creation. If ctx is done while polling, Wait returns
+// the context error and a later Wait resumes polling; once the task has
+// finished, every Wait returns the same result.
+func (t *VideoTask) Wait(ctx context.Context) (_ *VideoGenerationResult, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.r.recoverOperation(ctx, t.pending.op.name, &err)
+	if t.done {
+		return t.result, t.err
+	}
+
+	call := t.pending.call
+	ctx, cancel := call.withDeadline(ctx)
+	defer cancel()
+
+	result, err := t.r.finishOperation(ctx, t.pending, t.metadata)
+	if err != nil && ctx.Err() != nil && t.pending.stage == stagePoll {
+		return nil, call.deadlineError(ctx, stagePoll, err)
+	}
+	t.done = true
+	t.pending.release()
+	t.result, t.err = result, call.deadlineError(ctx, t.pending.stage, err)
+	return t.result, t.err
+}
+
+// cancelOperation records a caller-initiated cancellation: it optionally cancels
+// the Runway task, then meters the operation with stopReason CANCELLED
+func (r *ReveniumRunway) cancelOperation(p *pendingOperation, metadata *UsageMetadata, cancelTask bool) (*VideoGenerationResult, error) {
+	reason := "polling canceled by caller"
+	if cancelTask {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := p.snap.runway.CancelTask(ctx, p.taskID); err != nil {
+			Warn("%sFailed to cancel Runway task %s: %v", p.logPrefix(), p.taskID, err)
+		} else {
+			reason = "task canceled by caller"
+		}
+	}
+
+	Info("%sTask %s: %s", p.logPrefix(), p.taskID, reason)
+	result := buildResult(p, &TaskStatusResponse{ID: p.taskID, Status: TaskStatusCanceled})
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata["cancellationReason"] = reason
+
+	result, meterErr := r.meterResult(context.Background(), p, result, metadata, nil)
+	if meterErr != nil {
+		return result, meterErr
+	}
+	return result, NewTaskError(reason, context.Canceled)
+}
+
+// TaskHandle tracks an operation started with one of the Start* methods.
+// The task has already been created when the handle is returned; polling and
+// metering run in the background until Wait returns.
+type TaskHandle struct {
+	TaskID string // Runway task ID
+
+	done       chan struct{}
+	cancel     context.CancelFunc
+	canceled   atomic.Bool
+	cancelTask atomic.Bool
+	result     *VideoGenerationResult
+	err        error
+}
+
+// Done returns a channel that is closed when the operation has finished
+func (h *TaskHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait blocks until the operation finishes and returns its result. After Cancel,
+// the result has status CANCELED and the error is a task error.
+func (h *TaskHandle) Wait() (*VideoGenerationResult, error) {
+	<-h.done
+	return h.result, h.err
+}
+
+// Cancel stops waiting for the task. When cancelTask is true the Runway task
+// is also canceled. The operation is metered with stopReason CANCELLED and a
+// cancellationReason. Cancel has no effect once the operation has finished.
+func (h *TaskHandle) Cancel(cancelTask bool) {
+	if h.canceled.Swap(true) {
+		return
+	}
+	h.cancelTask.Store(cancelTask)
+	h.cancel()
+}
+
+// CancelFunc returns Cancel bound to cancelTask, for use where a plain func() is expected
+func (h *TaskHandle) CancelFunc(cancelTask bool) func() {
+	return func() { h.Cancel(cancelTask) }
+}
+
+// addGenerationMetadata stores the requested duration and the prompt on the
+// result so the metering client can include them in the payload. buildResult
+// removes the prompt again when capture is off for the operation.
+func addGenerationMetadata(result *VideoGenerationResult, requestedDuration int, promptText, negativePromptText string) {
+	result.Metadata = make(map[string]interface{})
+
+	// Store requested duration for metering (per-second billing)
+	if requestedDuration <= 0 {
+		requestedDuration = 5 // Runway default
+	}
+	result.RequestedDuration = requestedDuration
+	result.Metadata["requestedDuration"] = requestedDuration
+
+	// Store prompt for capture (used by metering client)
+	if promptText != "" {
+		result.Metadata["_capturedPrompt"] = promptText
+	}
+	if negativePromptText != "" {
+		result.Metadata["_capturedNegativePrompt"] = negativePromptText
+	}
+}
+
+// charsPerToken is the rough prompt characters per token used for promptTokens
+const charsPerToken = 4
+
+// addPromptMetrics records the prompt's size in characters and estimated tokens
+func addPromptMetrics(result *VideoGenerationResult, prompt string) {
+	chars := utf8.RuneCountInString(prompt)
+	result.Metadata["promptChars"] = chars
+	result.Metadata["promptTokens"] = (chars + charsPerToken - 1) / charsPerToken
+}
+
+// addRequestParams records the generation parameters of the originating
+// request so they are metered without callers copying them into Custom. Only
+// whether a seed was set is recorded, not its value.
+func addRequestParams(result *VideoGenerationResult, ratio string, seed *int, watermark *bool) {
+	if ratio != "" {
+		result.Metadata["ratio"] = ratio
+	}
+	result.Metadata["seedSet"] = seed != nil
+	if watermark != nil {
+		result.Metadata["watermark"] = *watermark
+	}
+}
+
+// minRatioPixels is the smallest side for which a request ratio is read as
+// pixel dimensions ("1280:768") rather than an aspect ratio ("16:9")
+const minRatioPixels = 100
+
+// addResolution records the output width, height and megapixels for
+// resolution-based pricing: from Runway's task metadata when it reports them,
+// otherwise from a pixel-dimension ratio in the request, flagged with
+// resolutionRequested since the output was not confirmed to match. Nothing is
+// recorded when neither is known.
+func addResolution(result *VideoGenerationResult, ratio string, status *TaskStatusResponse) {
+	width, height := 0, 0
+	requested := false
+	if status != nil {
+		width, height = intValue(status.Metadata["width"]), intValue(status.Metadata["height"])
+	}
+	if width <= 0 || height <= 0 {
+		width, height = parseRatioPixels(ratio)
+		requested = true
+	}
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	result.Metadata["width"] = width
+	result.Metadata["height"] = height
+	result.Metadata["megapixels"] = float64(width*height) / 1e6
+	if requested {
+		result.Metadata["resolutionRequested"] = true
+	}
+}
+
+// parseRatioPixels parses a "width:height" ratio in pixels, returning zeros
+// for aspect ratios and malformed values
+func parseRatioPixels(ratio string) (int, int) {
+	w, h, ok := strings.Cut(ratio, ":")
+	if !ok {
+		return 0, 0
+	}
+	width, errW := strconv.Atoi(strings.TrimSpace(w))
+	height, errH := strconv.Atoi(strings.TrimSpace(h))
+	if errW != nil || errH != nil || width < minRatioPixels || height < minRatioPixels {
+		return 0, 0
+	}
+	return width, height
+}
+
+// addFrameInfo copies the frame rate and frame count from Runway's task
+// metadata, when reported, onto the result
+func addFrameInfo(result *VideoGenerationResult, status *TaskStatusResponse) {
+	for _, key := range []string{"fps", "frameRate"} {
+		if fps := floatValue(status.Metadata[key]); fps > 0 {
+			result.FPS = fps
+			break
+		}
+	}
+	for _, key := range []string{"frameCount", "frames"} {
+		if frames := intValue(status.Metadata[key]); frames > 0 {
+			result.FrameCount = frames
+			break
+		}
+	}
+}
+
+// floatValue converts a JSON number to float64, returning 0 for anything else
+func floatValue(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// intValue converts a whole JSON number to int, returning 0 for anything else
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		if n == float64(int(n)) {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// addUpscaleMetadata records the source video duration for upscale billing.
+// The caller-supplied SourceDurationSeconds takes precedence over a duration in
+// Runway's task metadata; when neither is known, durationUnknown is set so the
+// record is not billed on a default duration.
+func addUpscaleMetadata(result *VideoGenerationResult, req *VideoUpscaleRequest, status *TaskStatusResponse) {
+	result.Metadata = make(map[string]interface{})
+
+	if req.SourceDurationSeconds > 0 {
+		result.Metadata["durationSeconds"] = req.SourceDurationSeconds
+		return
+	}
+
+	if status != nil {
+		for _, key := range []string{"durationSeconds", "duration"} {
+			if seconds, ok := status.Metadata[key].(float64); ok && seconds > 0 {
+				result.Metadata["durationSeconds"] = seconds
+				return
+			}
+		}
+	}
+
+	Debug("Source duration unknown for upscale task %s", result.ID)
+	result.Metadata["durationSeconds"] = 0.0
+	result.Metadata["durationUnknown"] = true
+}
+
+// logLifecycle logs a single Info line summarizing where an operation spent its time.
+// Queue and generation times are measured at polling granularity: queue time runs
+// from task creation until RUNNING was first observed, generation time from then
+// until a terminal status was observed.
+func logLifecycle(op *operation, result *VideoGenerationResult, startTime, createdAt time.Time, stats *pollStats, meteringEnqueued bool) {
+	createLatency := createdAt.Sub(startTime)
+
+	var queueTime, generationTime time.Duration
+	if stats != nil && !stats.CompletedAt.IsZero() {
+		if stats.FirstRunningAt.IsZero() {
+			// Never observed RUNNING; attribute the whole wait to generation
+			generationTime = stats.CompletedAt.Sub(createdAt)
+		} else {
+			queueTime = stats.FirstRunningAt.Sub(createdAt)
+			generationTime = stats.CompletedAt.Sub(stats.FirstRunningAt)
+		}
+	}
+
+	polls := 0
+	if stats != nil {
+		polls = stats.Polls
+	}
+
+	Info("%sTask lifecycle: operation=%s taskId=%s model=%s status=%s createLatency=%v polls=%d queueTime=%v generationTime=%v total=%v meteringEnqueued=%t",
+		logPrefix(result.CorrelationID), op.name, result.ID, result.Model, result.Status,
+		createLatency.Round(time.Millisecond), polls,
+		queueTime.Round(time.Millisecond), generationTime.Round(time.Millisecond),
+		result.Duration.Round(time.Millisecond), meteringEnqueued)
+}
+
+// CancelTask asks Runway to cancel a task to stop it consuming credits, for
+// example when a user abandons a render. An operation still polling the task
+// sees the CANCELED status and meters it with stopReason CANCELLED.
+// Cancellation is best-effort and races with task completion: a task that
+// finishes first is metered normally. To stop polling as well, use
+// TaskHandle.Cancel(true) for operations started with Start* methods.
+func (r *ReveniumRunway) CancelTask(ctx context.Context, taskID string) error {
+	if err := r.snapshot().runway.CancelTask(ctx, taskID); err != nil {
+		return err
+	}
+	Info("Requested cancellation of Runway task %s", taskID)
+	return nil
+}
+
+// MeteringCircuitState returns the state of the metering circuit breaker.
+// Reconfigure replaces the metering client, which starts with a closed circuit.
+func (r *ReveniumRunway) MeteringCircuitState() CircuitState {
+	return r.snapshot().metering.CircuitState()
+}
+
+// BuildMeteringPayload returns the metering record that would be sent for
+// result with metadata, merged over the configured default metadata, without
+// sending it; see MeteringClient.BuildMeteringPayload
+func (r *ReveniumRunway) BuildMeteringPayload(result *VideoGenerationResult, metadata *UsageMetadata) map[string]interface{} {
+	snap := r.snapshot()
+	return snap.metering.BuildMeteringPayload(result, mergeMetadata(snap.config.DefaultMetadata, metadata))
+}
+
+// Capabilities reports the operations and features supported by this
+// middleware version and which optional features the current configuration
+// enables. It makes no network calls.
+func (r *ReveniumRunway) Capabilities() Capabilities {
+	cfg := r.snapshot().config
+	return Capabilities{
+		Version:                GetVersion(),
+		Operations:             []string{OperationImageToVideo, OperationVideoToVideo, OperationTextToVideo, OperationVideoUpscale},
+		AsyncTasks:             true,
+		TextToVideo:            true,
+		ScheduledMetering:      cfg.MeteringSchedule > 0,
+		MeteringRequired:       cfg.MeteringRequired,
+		PerOutputMetering:      cfg.MeterPerOutput,
+		MeteringCircuitBreaker: cfg.MeteringCircuitThreshold >= 0,
+		CapturePrompts:         cfg.CapturePrompts,
+		ModelCapabilityCheck:   cfg.ModelCapabilityCheck,
+		ConcurrencyLimits:      cfg.MaxConcurrentTasks > 0 || len(cfg.ConcurrencyByModel) > 0,
+		OutputDurationProbing:  cfg.OutputDurationProber != nil,
+		RequestLogging:         cfg.RequestLogging,
+	}
+}
+
+// SupportedModels returns the models available from Runway, cached for the
+// configured ModelCacheTTL. If Runway's model list cannot be fetched, the
+// built-in registry (StaticModels) is returned instead and is not cached, so
+// the next call tries the endpoint again.
+func (r *ReveniumRunway) SupportedModels(ctx context.Context) ([]ModelInfo, error) {
+	snap := r.snapshot()
+	clock := snap.config.clock()
+
+	ttl := snap.config.ModelCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultModelCacheTTL
+	}
+
+	r.modelsMu.Lock()
+	defer r.modelsMu.Unlock()
+
+	if r.models != nil && clock.Since(r.modelsFetchedAt) < ttl {
+		return append([]ModelInfo(nil), r.models...), nil
+	}
+
+	models, err := snap.runway.ListModels(ctx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil || len(models) == 0 {
+		if err != nil {
+			Warn("Failed to fetch models from Runway, using built-in registry: %v", err)
+		} else {
+			Warn("Runway returned no models, using built-in registry")
+		}
+		return StaticModels(), nil
+	}
+
+	r.models = models
+	r.modelsFetchedAt = clock.Now()
+	Debug("Cached %d models from Runway", len(models))
+	return append([]ModelInfo(nil), models...), nil
+}
+
+// Verify performs a one-shot check of the Runway and Revenium credentials.
+// Neither check generates a video or records usage, so it is safe to call from
+// onboarding flows. The Runway key is verified against an authenticated
+// endpoint; the Revenium check confirms connectivity and catches keys the
+// health endpoint rejects, but cannot prove the key is valid (see
+// MeteringClient.VerifyCredentials). The returned result always contains per-service status; the
+// error is non-nil when either service failed, and wraps the first failure.
+func (r *ReveniumRunway) Verify(ctx context.Context) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	snap := r.snapshot()
+
+	clock := snap.config.clock()
+
+	start := clock.Now()
+	err := snap.runway.VerifyCredentials(ctx)
+	result.Runway = ServiceCheck{OK: err == nil, Latency: clock.Since(start), Error: err}
+	if err != nil {
+		Warn("Runway verification failed: %v", err)
+	}
+
+	start = clock.Now()
+	err = snap.metering.VerifyCredentials(ctx)
+	result.Revenium = ServiceCheck{OK: err == nil, Latency: clock.Since(start), Error: err}
+	if err != nil {
+		Warn("Revenium verification failed: %v", err)
+	}
+
+	if result.Runway.Error != nil {
+		return result, result.Runway.Error
+	}
+	if result.Revenium.Error != nil {
+		return result, result.Revenium.Error
+	}
+
+	Info("Verification passed (runway: %v, revenium: %v)", result.Runway.Latency, result.Revenium.Latency)
 	return result, nil
 }
 
 // sendMetering sends metering data asynchronously
-func (r *ReveniumRunway) sendMetering(ctx context.Context, result *VideoGenerationResult, metadata *UsageMetadata) {
+func (r *ReveniumRunway) sendMetering(ctx context.Context, meteringClient *MeteringClient, result *VideoGenerationResult, metadata *UsageMetadata) {
 	defer func() {
 		if rec := recover(); rec != nil {
-			Error("Metering goroutine panic: %v", rec)
+			Error("%sMetering goroutine panic: %v", logPrefix(result.CorrelationID), rec)
+		}
+	}()
+
+	if err := meteringClient.SendVideoMetering(ctx, result, metadata); err != nil {
+		Error("%sFailed to send metering data: %v", logPrefix(result.CorrelationID), err)
+	}
+}
+
+// queuedPayload is a prepared metering payload awaiting a scheduled send
+type queuedPayload struct {
+	client  *MeteringClient
+	payload map[string]interface{}
+	baseURL string // Per-record base URL override ("" uses the configured one)
+}
+
+// enqueuePayload adds a payload to the scheduled-send queue
+func (r *ReveniumRunway) enqueuePayload(client *MeteringClient, payload map[string]interface{}, baseURL string) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	r.queue = append(r.queue, queuedPayload{client: client, payload: payload, baseURL: baseURL})
+}
+
+// startMeteringSchedule sends queued payloads at the configured interval,
+// jittered when WithMeteringJitteredFlush is set, until Close
+func (r *ReveniumRunway) startMeteringSchedule() {
+	interval := r.config.MeteringSchedule
+	if interval <= 0 {
+		return
+	}
+	jitter := r.config.MeteringFlushJitter
+
+	go func() {
+		timer := time.NewTimer(jitterInterval(interval, jitter))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-timer.C:
+				r.sendQueued(r.asyncMeteringContext())
+				timer.Reset(jitterInterval(interval, jitter))
+			}
 		}
 	}()
+}
 
-	if err := r.meteringClient.SendVideoMetering(ctx, result, metadata); err != nil {
-		Error("Failed to send metering data: %v", err)
+// sendQueued sends every queued payload in a background goroutine tracked by
+// the metering WaitGroup
+func (r *ReveniumRunway) sendQueued(ctx context.Context) {
+	r.queueMu.Lock()
+	queued := r.queue
+	r.queue = nil
+	r.queueMu.Unlock()
+
+	if len(queued) == 0 {
+		return
 	}
+
+	Debug("Sending %d scheduled metering records", len(queued))
+	r.goMetering(func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				Error("Metering goroutine panic: %v", rec)
+			}
+		}()
+
+		for _, q := range queued {
+			if err := q.client.sendWithRetry(ctx, q.payload, q.baseURL); err != nil {
+				Error("Failed to send scheduled metering data: %v", err)
+			}
+		}
+	})
 }
 
-// Flush waits for all pending metering goroutines to complete.
+// goMetering runs a metering send in a goroutine tracked by the metering
+// WaitGroup and the in-flight count
+func (r *ReveniumRunway) goMetering(send func()) {
+	r.wg.Add(1)
+	r.inFlight.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.inFlight.Add(-1)
+		send()
+	}()
+}
+
+// InFlightMeteringCount returns the number of metering goroutines currently
+// running. A count that keeps growing means Revenium is slower than the rate
+// of operations and async metering is piling up.
+func (r *ReveniumRunway) InFlightMeteringCount() int {
+	return int(r.inFlight.Load())
+}
+
+// Flush waits for all pending metering goroutines to complete, first sending
+// any records queued by WithMeteringSchedule.
 // Call this before program exit to ensure all metering data is sent.
+// Operations started with Start* methods are metered when they finish, so
+// wait on their TaskHandles first. After Close, Flush returns immediately:
+// Close has already sent or canceled all pending metering.
 func (r *ReveniumRunway) Flush() {
+	r.sendQueued(r.asyncMeteringContext())
 	r.wg.Wait()
 }
 
+// FlushContext waits for pending metering like Flush, but gives up when ctx is
+// done. On timeout, in-flight metering is canceled - including any retry
+// backoff in progress - so shutdown is not blocked by a failing endpoint.
+// Canceled records are logged and dropped. Metering started afterwards is
+// unaffected.
+func (r *ReveniumRunway) FlushContext(ctx context.Context) error {
+	r.sendQueued(r.asyncMeteringContext())
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	Warn("Flush deadline reached; canceling pending metering")
+	r.mu.Lock()
+	r.meteringCancel()
+	r.meteringCtx, r.meteringCancel = context.WithCancel(context.Background())
+	r.mu.Unlock()
+
+	<-done
+	return ctx.Err()
+}
+
+// WaitForMetering blocks until no metering sends are in flight, or ctx is
+// done. Unlike FlushContext it neither sends queued scheduled records nor
+// cancels in-flight sends on timeout, so tests can use it in place of sleeping
+// after an operation without changing what is metered.
+func (r *ReveniumRunway) WaitForMetering(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// asyncMeteringContext returns the detached context for fire-and-forget metering
+func (r *ReveniumRunway) asyncMeteringContext() context.Context {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.meteringCtx
+}
+
 // Close closes the client and cleans up resources.
-// It waits for pending metering operations before closing.
+// It waits for background tasks and then for pending metering operations, the
+// latter for at most the close timeout (see WithCloseTimeout). Metering still
+// pending then is canceled, and Close returns a MeteringError after closing.
+// Close is idempotent and safe to call concurrently: the first call does the
+// work, concurrent calls wait for it to finish, and later calls return nil.
 func (r *ReveniumRunway) Close() error {
-	// Wait for pending metering operations
-	r.Flush()
+	var err error
+	r.closeOnce.Do(func() {
+		err = r.close()
+	})
+	return err
+}
+
+// close stops background goroutines, drains pending work and closes the clients
+func (r *ReveniumRunway) close() error {
+	// Stop background goroutines
+	if r.stop != nil {
+		close(r.stop)
+	}
+
+	// Wait for background tasks, then pending metering operations
+	r.tasks.Wait()
+	flushErr := r.flushForClose()
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -326,10 +1561,31 @@ func (r *ReveniumRunway) Close() error {
 		return err
 	}
 
+	return flushErr
+}
+
+// flushForClose flushes pending metering within the configured close timeout
+func (r *ReveniumRunway) flushForClose() error {
+	timeout := r.snapshot().config.CloseTimeout
+	if timeout < 0 {
+		r.Flush()
+		return nil
+	}
+	if timeout == 0 {
+		timeout = DefaultCloseTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := r.FlushContext(ctx); err != nil {
+		return NewMeteringError(fmt.Sprintf("pending metering canceled after %v close timeout", timeout), err)
+	}
 	return nil
 }
 
-// Reset resets the global middleware state for testing
+// Reset resets the global middleware state for testing: it closes the global
+// client and clears the initialization flag. The logger set with SetLogger and
+// the cached middleware version are kept; use ResetAll to clear them too.
 func Reset() {
 	globalMu.Lock()
 	defer globalMu.Unlock()
@@ -341,3 +1597,56 @@ func Reset() {
 
 	initialized = false
 }
+
+// ResetAll resets the global state like Reset, and also restores the default
+// logger and clears the cached middleware version, giving each test a clean
+// slate. It must not run concurrently with other use of the package.
+func ResetAll() {
+	Reset()
+	SetLogger(NewDefaultLogger())
+	resetMiddlewareSource()
+}
+
+// State is a snapshot of the package-level state used by Initialize,
+// GetClient and the package logging functions
+type State struct {
+	client      *ReveniumRunway
+	initialized bool
+	logger      Logger
+}
+
+// SaveState captures the global client, initialization flag and logger so a
+// test can restore them when it finishes:
+//
+//	saved := revenium.SaveState()
+//	t.Cleanup(func() {
+//		revenium.Reset()
+//		revenium.RestoreState(saved)
+//	})
+//
+// Tests that run in parallel should not share the globals at all; create an
+// isolated client per test with NewReveniumRunway instead.
+func SaveState() *State {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return &State{
+		client:      globalClient,
+		initialized: initialized,
+		logger:      GetLogger(),
+	}
+}
+
+// RestoreState reinstates state captured by SaveState. A global client created
+// after the snapshot is not closed; call Reset first to close it.
+func RestoreState(s *State) {
+	if s == nil {
+		return
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalClient = s.client
+	initialized = s.initialized
+	if s.logger != nil {
+		SetLogger(s.logger)
+	}
+}
@@ -0,0 +1,324 @@
+package revenium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestConcurrentOperationsWithReconfigure runs operations while the
+// configuration is swapped; run with -race to check the snapshot handling
+func TestConcurrentOperationsWithReconfigure(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering)
+
+	const operations = 20
+	ctx := context.Background()
+	done := make(chan struct{})
+
+	var reconfigures sync.WaitGroup
+	reconfigures.Add(1)
+	go func() {
+		defer reconfigures.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			err := r.Reconfigure(
+				WithAppVersion(fmt.Sprintf("1.0.%d", i)),
+				WithCapturePrompts(i%2 == 0),
+				WithPollingConfig(fastPolling()),
+			)
+			if err != nil {
+				t.Errorf("Reconfigure() error = %v", err)
+				return
+			}
+			_ = r.GetConfig()
+		}
+	}()
+
+	var ops sync.WaitGroup
+	for i := 0; i < operations; i++ {
+		ops.Add(1)
+		go func(i int) {
+			defer ops.Done()
+			req := &TextToVideoRequest{PromptText: fmt.Sprintf("scene %d", i), Duration: 5}
+			result, err := r.TextToVideo(ctx, req, &UsageMetadata{OrganizationID: "org"})
+			if err != nil {
+				t.Errorf("TextToVideo() error = %v", err)
+				return
+			}
+			if result.Status != TaskStatusSucceeded {
+				t.Errorf("TextToVideo() status = %s, want %s", result.Status, TaskStatusSucceeded)
+			}
+		}(i)
+	}
+	ops.Wait()
+	close(done)
+	reconfigures.Wait()
+
+	flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := r.FlushContext(flushCtx); err != nil {
+		t.Fatalf("FlushContext() error = %v", err)
+	}
+	if got := len(metering.received()); got != operations {
+		t.Errorf("metering records = %d, want %d", got, operations)
+	}
+}
+
+func TestCloseConcurrentlyAndFlushAfterClose(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	// A long schedule keeps the record queued until Close sends it
+	r := newTestClient(t, runway, metering, WithMeteringSchedule(time.Hour))
+
+	ctx := context.Background()
+	if _, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a lighthouse"}, nil); err != nil {
+		t.Fatalf("TextToVideo() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = r.Close()
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Close() call %d error = %v", i+1, err)
+		}
+	}
+	if got := len(metering.received()); got != 1 {
+		t.Errorf("metering records after Close = %d, want 1", got)
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		r.Flush()
+		close(flushed)
+	}()
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatal("Flush() after Close did not return")
+	}
+	if got := len(metering.received()); got != 1 {
+		t.Errorf("metering records after Flush = %d, want 1", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("third Close() error = %v", err)
+	}
+}
+
+func TestVideoTaskKeepsSlotWhenWaitIsInterrupted(t *testing.T) {
+	var finished atomic.Bool
+	runway := newFakeRunway(t)
+	runway.status = func(taskID string) *TaskStatusResponse {
+		if finished.Load() {
+			return nil
+		}
+		return &TaskStatusResponse{ID: taskID, Status: TaskStatusRunning}
+	}
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithMaxConcurrentTasks(1), WithMeteringRequired(true))
+
+	ctx := context.Background()
+	task, err := r.SubmitTextToVideo(ctx, &TextToVideoRequest{PromptText: "first"}, nil)
+	if err != nil {
+		t.Fatalf("SubmitTextToVideo() error = %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := task.Wait(waitCtx); err == nil {
+		t.Fatal("Wait() on a running task with an expiring context returned no error")
+	}
+
+	// The interrupted task still holds the only slot
+	submitCtx, cancelSubmit := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelSubmit()
+	if _, err := r.SubmitTextToVideo(submitCtx, &TextToVideoRequest{PromptText: "second"}, nil); err == nil {
+		t.Fatal("SubmitTextToVideo() acquired a slot held by an interrupted VideoTask")
+	}
+
+	finished.Store(true)
+	result, err := task.Wait(ctx)
+	if err != nil {
+		t.Fatalf("resumed Wait() error = %v", err)
+	}
+	if result.Status != TaskStatusSucceeded {
+		t.Errorf("resumed Wait() status = %s, want %s", result.Status, TaskStatusSucceeded)
+	}
+
+	// Finishing the task frees the slot
+	next, err := r.SubmitTextToVideo(ctx, &TextToVideoRequest{PromptText: "third"}, nil)
+	if err != nil {
+		t.Fatalf("SubmitTextToVideo() after the task finished error = %v", err)
+	}
+	if _, err := next.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func TestCanceledOperationReleasesSlot(t *testing.T) {
+	runway := newFakeRunway(t)
+	runway.status = func(taskID string) *TaskStatusResponse {
+		if taskID == "task-1" {
+			return &TaskStatusResponse{ID: taskID, Status: TaskStatusRunning}
+		}
+		return nil
+	}
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithMaxConcurrentTasks(1))
+
+	ctx := context.Background()
+	opCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.TextToVideo(opCtx, &TextToVideoRequest{PromptText: "never finishes"}, nil); err == nil {
+		t.Fatal("TextToVideo() with an expiring context returned no error")
+	}
+
+	nextCtx, cancelNext := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelNext()
+	if _, err := r.TextToVideo(nextCtx, &TextToVideoRequest{PromptText: "next"}, nil); err != nil {
+		t.Fatalf("TextToVideo() after a canceled operation error = %v", err)
+	}
+}
+
+// TestWaitForMeteringEndToEnd replaces a sleep after an operation: the
+// asynchronous metering record has arrived once WaitForMetering returns
+func TestWaitForMeteringEndToEnd(t *testing.T) {
+	runway := newFakeRunway(t)
+	release := make(chan struct{})
+	var received atomic.Int32
+	metering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(metering.Close)
+	r := newTestClient(t, runway, &fakeMetering{Server: metering})
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	t.Cleanup(unblock) // Runs before Close so a failed test does not hang
+
+	ctx := context.Background()
+	if _, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a harbor at night"}, nil); err != nil {
+		t.Fatalf("TextToVideo() error = %v", err)
+	}
+
+	// The send is still blocked, so a bounded wait times out
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := r.WaitForMetering(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForMetering() with a pending send error = %v, want context.DeadlineExceeded", err)
+	}
+
+	unblock()
+	waitCtx, cancelWait := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelWait()
+	if err := r.WaitForMetering(waitCtx); err != nil {
+		t.Fatalf("WaitForMetering() error = %v", err)
+	}
+	if got := received.Load(); got != 1 {
+		t.Errorf("metering records after WaitForMetering = %d, want 1", got)
+	}
+}
+
+func TestRequiredMeteringFailureIsReturned(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(metering.Close)
+	r := newTestClient(t, runway, &fakeMetering{Server: metering}, WithMeteringRequired(true))
+
+	result, err := r.TextToVideo(context.Background(), &TextToVideoRequest{PromptText: "a quiet street"}, nil)
+	if !IsMeteringError(err) {
+		t.Fatalf("TextToVideo() error = %v, want a MeteringError", err)
+	}
+	if result == nil || result.Status != TaskStatusSucceeded {
+		t.Errorf("TextToVideo() result = %+v, want the succeeded result alongside the error", result)
+	}
+}
+
+func TestAsyncMeteringFailureIsNotReturned(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(metering.Close)
+	r := newTestClient(t, runway, &fakeMetering{Server: metering})
+
+	if _, err := r.TextToVideo(context.Background(), &TextToVideoRequest{PromptText: "a quiet street"}, nil); err != nil {
+		t.Errorf("TextToVideo() with background metering error = %v", err)
+	}
+}
+
+func TestScheduledMeteringQueuesUntilFlush(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithMeteringSchedule(time.Hour))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: fmt.Sprintf("scene %d", i)}, nil); err != nil {
+			t.Fatalf("TextToVideo() error = %v", err)
+		}
+	}
+	if err := r.WaitForMetering(ctx); err != nil {
+		t.Fatalf("WaitForMetering() error = %v", err)
+	}
+	if got := len(metering.received()); got != 0 {
+		t.Fatalf("metering records before the schedule fired = %d, want 0", got)
+	}
+
+	r.Flush()
+	if got := len(metering.received()); got != 2 {
+		t.Errorf("metering records after Flush = %d, want 2", got)
+	}
+}
+
+func TestScheduledMeteringSendsOnInterval(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithMeteringSchedule(10*time.Millisecond))
+
+	if _, err := r.TextToVideo(context.Background(), &TextToVideoRequest{PromptText: "a bridge"}, nil); err != nil {
+		t.Fatalf("TextToVideo() error = %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(metering.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduled send did not deliver the queued record")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRequiredMeteringBypassesSchedule(t *testing.T) {
+	runway := newFakeRunway(t)
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering, WithMeteringSchedule(time.Hour), WithMeteringRequired(true))
+
+	if _, err := r.TextToVideo(context.Background(), &TextToVideoRequest{PromptText: "a bridge"}, nil); err != nil {
+		t.Fatalf("TextToVideo() error = %v", err)
+	}
+	if got := len(metering.received()); got != 1 {
+		t.Errorf("metering records with required metering = %d, want 1", got)
+	}
+}
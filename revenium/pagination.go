@@ -0,0 +1,67 @@
+package revenium
+
+import "context"
+
+// PageFetcher fetches one page of a list endpoint. cursor is "" for the first
+// page; an empty next cursor marks the last page.
+type PageFetcher[T any] func(ctx context.Context, cursor string) (items []T, next string, err error)
+
+// Iterator walks the items of a paginated list endpoint, fetching pages as
+// needed so callers never handle cursors. It is not safe for concurrent use.
+type Iterator[T any] struct {
+	fetch  PageFetcher[T]
+	page   []T    // Unreturned items of the current page
+	cursor string // Cursor of the next page
+	last   bool   // The last page has been fetched
+	err    error  // Sticky fetch error
+}
+
+// NewIterator creates an iterator over the pages returned by fetch
+func NewIterator[T any](fetch PageFetcher[T]) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch}
+}
+
+// Next returns the next item. It returns false once the items are exhausted or
+// a page fetch fails; a failure is returned again by every later call.
+func (it *Iterator[T]) Next(ctx context.Context) (T, bool, error) {
+	var zero T
+	for len(it.page) == 0 {
+		if it.err != nil {
+			return zero, false, it.err
+		}
+		if it.last {
+			return zero, false, nil
+		}
+
+		items, next, err := it.fetch(ctx, it.cursor)
+		if err != nil {
+			it.err = err
+			return zero, false, err
+		}
+		if next != "" && next == it.cursor {
+			it.err = NewProviderError("list endpoint returned the same page cursor twice", nil).
+				WithDetails("cursor", next)
+			return zero, false, it.err
+		}
+		it.page, it.cursor, it.last = items, next, next == ""
+	}
+
+	item := it.page[0]
+	it.page = it.page[1:]
+	return item, true, nil
+}
+
+// All collects the remaining items. On error it returns the items read so far.
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for {
+		item, ok, err := it.Next(ctx)
+		if err != nil {
+			return items, err
+		}
+		if !ok {
+			return items, nil
+		}
+		items = append(items, item)
+	}
+}
@@ -0,0 +1,115 @@
+package revenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// pages returns a PageFetcher serving the given pages in order, keyed by the
+// cursor that requests them, and records the cursors it was called with
+func pages(t *testing.T, byCursor map[string][]int, next map[string]string, calls *[]string) PageFetcher[int] {
+	t.Helper()
+	return func(ctx context.Context, cursor string) ([]int, string, error) {
+		*calls = append(*calls, cursor)
+		items, ok := byCursor[cursor]
+		if !ok {
+			t.Fatalf("unexpected cursor %q", cursor)
+		}
+		return items, next[cursor], nil
+	}
+}
+
+func TestIteratorWalksPages(t *testing.T) {
+	var calls []string
+	it := NewIterator(pages(t,
+		map[string][]int{"": {1, 2}, "b": {}, "c": {3}},
+		map[string]string{"": "b", "b": "c"},
+		&calls,
+	))
+
+	got, err := it.All(context.Background())
+	if err != nil {
+		t.Fatalf("All() error = %v", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+	if want := []string{"", "b", "c"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("fetched cursors = %q, want %q", calls, want)
+	}
+
+	// Exhausted iterators do not fetch again
+	if _, ok, err := it.Next(context.Background()); ok || err != nil {
+		t.Errorf("Next() after the last page = %v, %v; want false, nil", ok, err)
+	}
+	if len(calls) != 3 {
+		t.Errorf("fetches after exhaustion = %d, want 3", len(calls))
+	}
+}
+
+func TestIteratorErrorIsSticky(t *testing.T) {
+	fetchErr := errors.New("boom")
+	calls := 0
+	it := NewIterator(func(ctx context.Context, cursor string) ([]int, string, error) {
+		calls++
+		if cursor == "" {
+			return []int{1}, "next", nil
+		}
+		return nil, "", fetchErr
+	})
+
+	got, err := it.All(context.Background())
+	if !errors.Is(err, fetchErr) {
+		t.Fatalf("All() error = %v, want %v", err, fetchErr)
+	}
+	if want := []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("All() items before the error = %v, want %v", got, want)
+	}
+	if _, ok, err := it.Next(context.Background()); ok || !errors.Is(err, fetchErr) {
+		t.Errorf("Next() after a failure = %v, %v; want false, %v", ok, err, fetchErr)
+	}
+	if calls != 2 {
+		t.Errorf("fetches = %d, want 2", calls)
+	}
+}
+
+func TestIteratorRejectsRepeatedCursor(t *testing.T) {
+	it := NewIterator(func(ctx context.Context, cursor string) ([]int, string, error) {
+		return nil, "same", nil
+	})
+	if _, err := it.All(context.Background()); !IsProviderError(err) {
+		t.Errorf("All() error = %v, want a ProviderError", err)
+	}
+}
+
+func TestListModelsFollowsPages(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+		response := map[string]interface{}{"models": []ModelInfo{{ID: "gen4_turbo"}}, "nextCursor": "page 2"}
+		if cursor == "page 2" {
+			response = map[string]interface{}{"models": []ModelInfo{{ID: "gen3a_turbo"}}}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.RunwayBaseURL = server.URL
+	models, err := NewRunwayClient(cfg).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 || models[0].ID != "gen4_turbo" || models[1].ID != "gen3a_turbo" {
+		t.Errorf("ListModels() = %+v, want gen4_turbo then gen3a_turbo", models)
+	}
+	if want := []string{"", "page 2"}; !reflect.DeepEqual(cursors, want) {
+		t.Errorf("requested cursors = %q, want %q", cursors, want)
+	}
+}
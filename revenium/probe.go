@@ -0,0 +1,158 @@
+package revenium
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DurationProber returns the duration in seconds of the video at url
+type DurationProber func(ctx context.Context, url string) (float64, error)
+
+// probeChunkSize is how much of each end of the file ProbeMP4Duration reads
+const probeChunkSize = 512 * 1024
+
+// maxProbedDuration bounds plausible durations, rejecting false mvhd matches
+const maxProbedDuration = 24 * time.Hour
+
+// probeHTTPClient fetches output videos for duration probing. Output URLs are
+// pre-signed, so no credentials are sent.
+var probeHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// ProbeMP4Duration is the default DurationProber. It reads the start of the
+// file with a range request and, if the movie header is not there (moov after
+// mdat), the end of the file, then parses the duration from the mvhd box.
+func ProbeMP4Duration(ctx context.Context, url string) (float64, error) {
+	head, partial, err := fetchRange(ctx, url, fmt.Sprintf("bytes=0-%d", probeChunkSize-1))
+	if err != nil {
+		return 0, err
+	}
+	if seconds, ok := parseMVHDDuration(head); ok {
+		return seconds, nil
+	}
+	if !partial {
+		// The server ignored the range; a suffix request would return the head again
+		return 0, NewProviderError("movie header not found in output video", nil)
+	}
+
+	tail, _, err := fetchRange(ctx, url, fmt.Sprintf("bytes=-%d", probeChunkSize))
+	if err != nil {
+		return 0, err
+	}
+	if seconds, ok := parseMVHDDuration(tail); ok {
+		return seconds, nil
+	}
+	return 0, NewProviderError("movie header not found in output video", nil)
+}
+
+// fetchRange reads up to probeChunkSize bytes of the requested range and
+// reports whether the server honored the range
+func fetchRange(ctx context.Context, url, byteRange string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, NewProviderError("failed to create probe request", err)
+	}
+	req.Header.Set("Range", byteRange)
+
+	resp, err := probeHTTPClient.Do(req)
+	if err != nil {
+		return nil, false, NewNetworkError("probe request failed", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, false, NewProviderError(fmt.Sprintf("probe request returned status %d", resp.StatusCode), nil)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, probeChunkSize))
+	if err != nil {
+		return nil, false, NewNetworkError("failed to read output video", err)
+	}
+	return data, resp.StatusCode == http.StatusPartialContent, nil
+}
+
+// parseMVHDDuration finds an mvhd box in data and returns the movie duration
+// in seconds
+func parseMVHDDuration(data []byte) (float64, bool) {
+	for offset := 0; ; {
+		i := bytes.Index(data[offset:], []byte("mvhd"))
+		if i < 0 {
+			return 0, false
+		}
+		body := data[offset+i+4:]
+		offset += i + 4
+
+		if len(body) < 4 {
+			return 0, false
+		}
+		var timescale uint32
+		var duration uint64
+		switch body[0] { // version; flags follow in the next three bytes
+		case 0:
+			if len(body) < 20 {
+				continue
+			}
+			timescale = binary.BigEndian.Uint32(body[12:16])
+			duration = uint64(binary.BigEndian.Uint32(body[16:20]))
+		case 1:
+			if len(body) < 32 {
+				continue
+			}
+			timescale = binary.BigEndian.Uint32(body[20:24])
+			duration = binary.BigEndian.Uint64(body[24:32])
+		default:
+			continue
+		}
+
+		if timescale == 0 || duration == 0 {
+			continue
+		}
+		seconds := float64(duration) / float64(timescale)
+		if seconds > maxProbedDuration.Seconds() {
+			continue
+		}
+		return seconds, true
+	}
+}
+
+// probeOutputDuration fills in the delivered duration of a successful result
+// from its first output when Runway did not report one. Failures are logged
+// and leave the metadata unchanged, so metering falls back to the requested
+// duration.
+func probeOutputDuration(ctx context.Context, cfg *Config, result *VideoGenerationResult) {
+	prober := cfg.OutputDurationProber
+	if prober == nil || result.Status != TaskStatusSucceeded || len(result.OutputURLs) == 0 {
+		return
+	}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	if hasDeliveredDuration(result.Metadata) {
+		return
+	}
+
+	seconds, err := prober(ctx, result.OutputURLs[0])
+	if err != nil || seconds <= 0 {
+		Warn("%sFailed to probe output duration for task %s: %v", logPrefix(result.CorrelationID), result.ID, err)
+		return
+	}
+
+	Debug("Probed output duration for task %s: %.2fs", result.ID, seconds)
+	result.Metadata["durationSeconds"] = seconds
+	delete(result.Metadata, "durationUnknown")
+}
+
+// hasDeliveredDuration reports whether the metadata carries a known delivered
+// video duration
+func hasDeliveredDuration(metadata map[string]interface{}) bool {
+	if unknown, _ := metadata["durationUnknown"].(bool); unknown {
+		return false
+	}
+	_, hasDuration := metadata["duration"]
+	_, hasSeconds := metadata["durationSeconds"]
+	return hasDuration || hasSeconds
+}
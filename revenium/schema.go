@@ -0,0 +1,258 @@
+package revenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// payloadField describes a field the middleware can emit in a metering payload
+type payloadField struct {
+	Name        string // JSON key
+	Type        string // JSON Schema type
+	Required    bool   // Present in every payload
+	Description string
+}
+
+// meteringPayloadFields is the single source of truth for the fields
+// buildMeteringPayload can emit. It drives MeteringPayloadSchema and the
+// reserved-key check applied to UsageMetadata.Custom, so adding a field to the
+// payload means adding it here. TestPayloadFieldsMatchRegistry fails when a
+// payload key is missing here or a field here is never emitted.
+var meteringPayloadFields = []payloadField{
+	// Core fields (always present)
+	{"operationType", "string", true, "Always VIDEO"},
+	{"operationSubtype", "string", false, "Generation that produced the video: image-to-video, video-to-video, text-to-video or video-upscale"},
+	{"provider", "string", true, "AI provider name"},
+	{"modelSource", "string", true, "Model source identifier"},
+	{"model", "string", true, "Runway model used for the task"},
+	{"transactionId", "string", true, "Runway task ID"},
+	{"requestTime", "string", true, "RFC 3339 time the operation started"},
+	{"responseTime", "string", true, "RFC 3339 time the payload was built"},
+	{"requestDuration", "integer", true, "Total operation time in milliseconds, including polling"},
+	{"durationSeconds", "number", false, "Generated video duration in seconds (billing basis for PER_SECOND); omitted for operations without a duration"},
+	{"requestedDurationSeconds", "number", false, "Video duration requested from Runway in seconds; omitted with durationSeconds"},
+	{"stopReason", "string", true, "END, ERROR or CANCELLED, unless overridden by WithStopReasonClassifier; IN_PROGRESS on lifecycle start records"},
+	{"costType", "string", true, "Always AI"},
+	{"isStreamed", "boolean", true, "Always false"},
+	{"middlewareSource", "string", true, "Middleware name and version"},
+	{"billingBasis", "string", true, "PER_SECOND or PER_GENERATION"},
+	{"sequence", "integer", true, "Per-client sequence number, scoped to clientNonce"},
+	{"clientNonce", "string", true, "Random identifier of the metering client instance"},
+
+	// Optional middleware fields
+	{"quantity", "integer", false, "Number of billable generations (PER_GENERATION only)"},
+	{"estimatedCredits", "number", false, "Client-side credit estimate from WithPricingTable"},
+	{"estimatedCost", "number", false, "Client-side cost estimate from WithPricingTable"},
+	{"currency", "string", false, "Currency of estimatedCost"},
+	{"pollCount", "integer", false, "Number of task status polls made while waiting for the task"},
+	{"fps", "number", false, "Output frame rate, when reported by Runway or derived"},
+	{"frameCount", "integer", false, "Output frame count, when reported by Runway or derived"},
+	{"fpsDerived", "boolean", false, "Set when fps was computed from frameCount and durationSeconds"},
+	{"frameCountDerived", "boolean", false, "Set when frameCount was computed from durationSeconds and fps"},
+	{"requestBytes", "integer", false, "Request body bytes sent to Runway (WithByteCounts)"},
+	{"responseBytes", "integer", false, "Response body bytes received from Runway (WithByteCounts)"},
+	{"errorReason", "string", false, "Runway error message for failed tasks"},
+	{"failureCode", "string", false, "Runway failure code for failed tasks"},
+	{"requestedDuration", "integer", false, "Requested duration as sent to Runway"},
+	{"ratio", "string", false, "Output resolution ratio from the request (image-to-video)"},
+	{"seedSet", "boolean", false, "Whether the request set a seed"},
+	{"watermark", "boolean", false, "Watermark setting from the request, when set"},
+	{"width", "integer", false, "Output width in pixels, when known"},
+	{"height", "integer", false, "Output height in pixels, when known"},
+	{"megapixels", "number", false, "Output pixels per frame in millions (width * height / 10^6)"},
+	{"resolutionRequested", "boolean", false, "Set when width and height come from the request ratio rather than Runway's task metadata"},
+	{"durationUnknown", "boolean", false, "Set when the video duration could not be determined (durationSeconds is 0)"},
+	{"appVersion", "string", false, "Host application version (WithAppVersion)"},
+	{"correlationId", "string", false, "Operation correlation ID (WithCorrelationIDGenerator)"},
+	{"outputIndex", "integer", false, "Zero-based index of the output this record bills (WithPerOutputMetering)"},
+	{"lifecycleEvent", "string", false, "START or COMPLETE (WithLifecycleMetering)"},
+	{"outputCount", "integer", false, "Number of outputs the operation produced (WithPerOutputMetering)"},
+	{"cancellationReason", "string", false, "Why the operation was canceled"},
+	{"inputMessages", "string", false, "Captured prompt as a JSON message array (WithCapturePrompts)"},
+	{"outputResponse", "string", false, "Generated video URLs as a JSON array (WithCapturePrompts)"},
+	{"promptsTruncated", "boolean", false, "Whether the captured prompt was truncated"},
+	{"promptChars", "integer", false, "Prompt length in Unicode characters (WithPromptMetrics)"},
+	{"promptTokens", "integer", false, "Estimated prompt tokens at four characters per token (WithPromptMetrics)"},
+	{"payloadTrimmed", "boolean", false, "Whether prompts or custom fields were dropped to fit WithMaxPayloadBytes"},
+
+	// UsageMetadata fields
+	{"organizationId", "string", false, "Organization identifier"},
+	{"productId", "string", false, "Product identifier"},
+	{"taskType", "string", false, "Caller-defined task type"},
+	{"agent", "string", false, "Agent or worker identifier"},
+	{"subscriptionId", "string", false, "Subscription identifier"},
+	{"traceId", "string", false, "Trace identifier"},
+	{"parentTransactionId", "string", false, "Parent transaction for distributed tracing"},
+	{"traceType", "string", false, "Trace type"},
+	{"traceName", "string", false, "Trace name"},
+	{"environment", "string", false, "Deployment environment"},
+	{"region", "string", false, "Deployment region"},
+	{"retryNumber", "integer", false, "Caller retry attempt number"},
+	{"originalTransactionId", "string", false, "transactionId of the first attempt, when retryNumber > 0"},
+	{"credentialAlias", "string", false, "Alias of the credential used"},
+	{"subscriber", "object", false, "Subscriber details"},
+	{"taskId", "string", false, "Caller-defined task identifier"},
+	{"responseQualityScore", "number", false, "Caller-assigned quality score"},
+	{"videoJobId", "string", false, "Multimodal video job identifier"},
+	{"audioJobId", "string", false, "Multimodal audio job identifier"},
+	{"custom", "object", false, "Custom fields, when WithCustomNamespace nests them instead of merging them at the top level"},
+}
+
+// reservedPayloadKeys indexes meteringPayloadFields by name
+var reservedPayloadKeys = func() map[string]bool {
+	keys := make(map[string]bool, len(meteringPayloadFields))
+	for _, f := range meteringPayloadFields {
+		keys[f.Name] = true
+	}
+	return keys
+}()
+
+// IsReservedPayloadKey reports whether key is a field the middleware emits.
+// UsageMetadata.Custom entries with reserved keys are ignored.
+func IsReservedPayloadKey(key string) bool {
+	return reservedPayloadKeys[key]
+}
+
+// ReservedPayloadKeys returns the sorted list of keys Custom fields must not use
+func ReservedPayloadKeys() []string {
+	keys := make([]string, 0, len(meteringPayloadFields))
+	for _, f := range meteringPayloadFields {
+		keys = append(keys, f.Name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MeteringPayloadSchema returns a JSON Schema describing every field the
+// middleware can emit in a metering payload. Additional properties are allowed
+// because UsageMetadata.Custom keys are merged into the payload; the reserved
+// key list is included under "x-reservedKeys".
+func MeteringPayloadSchema() []byte {
+	properties := make(map[string]interface{}, len(meteringPayloadFields))
+	var required []string
+	for _, f := range meteringPayloadFields {
+		properties[f.Name] = map[string]interface{}{
+			"type":        f.Type,
+			"description": f.Description,
+		}
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Revenium Runway video metering payload",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": true,
+		"x-reservedKeys":       ReservedPayloadKeys(),
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		// The schema is built from static data, so this cannot happen in practice
+		Error("Failed to marshal metering payload schema: %v", err)
+		return nil
+	}
+	return data
+}
+
+// validatePayload checks that a payload has every required field and can be
+// serialized, returning a ValidationError describing the first problem
+func validatePayload(payload map[string]interface{}) error {
+	for _, f := range meteringPayloadFields {
+		if _, ok := payload[f.Name]; f.Required && !ok {
+			return NewValidationError(fmt.Sprintf("metering payload is missing required field %q", f.Name), nil)
+		}
+	}
+	if _, err := json.Marshal(payload); err != nil {
+		return NewValidationError("metering payload is not JSON-serializable", err)
+	}
+	return nil
+}
+
+// FieldDiff compares one field across two metering payloads
+type FieldDiff struct {
+	Field string      // JSON key
+	A     interface{} // Value in the first payload (nil when absent)
+	B     interface{} // Value in the second payload (nil when absent)
+	InA   bool        // Field is present in the first payload
+	InB   bool        // Field is present in the second payload
+	Equal bool        // Present in both with the same JSON value
+}
+
+// DiffPayloads compares two metering payloads field by field and returns one
+// FieldDiff per key present in either, sorted by key. Values are compared by
+// their JSON encoding, so a payload read back from JSON compares equal to the
+// one it was built from. It is intended for hard-coding checks: build payloads
+// for two scenarios with different metadata and assert that every
+// user-settable field differs.
+func DiffPayloads(a, b map[string]interface{}) []FieldDiff {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	diffs := make([]FieldDiff, 0, len(keys))
+	for _, k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		diffs = append(diffs, FieldDiff{
+			Field: k,
+			A:     va,
+			B:     vb,
+			InA:   inA,
+			InB:   inB,
+			Equal: inA && inB && sameJSONValue(va, vb),
+		})
+	}
+	return diffs
+}
+
+// DefaultPayloadValues returns the values the middleware fills in when a
+// result or request leaves them unset: Runway's 5 second default duration and
+// the END stop reason. Pass it, or an extended copy, to AssertNoHardcodedValues.
+func DefaultPayloadValues() map[string]interface{} {
+	return map[string]interface{}{
+		"durationSeconds":          5.0,
+		"requestedDurationSeconds": 5.0,
+		"stopReason":               "END",
+	}
+}
+
+// AssertNoHardcodedValues returns, sorted, the payload fields whose value
+// equals the one in knownDefaults (compared by JSON encoding, like
+// DiffPayloads). A scenario that sets every field explicitly should get an
+// empty list; any field returned may have fallen back to a library default or
+// been hard-coded.
+func AssertNoHardcodedValues(payload map[string]interface{}, knownDefaults map[string]interface{}) []string {
+	var fields []string
+	for k, def := range knownDefaults {
+		if v, ok := payload[k]; ok && sameJSONValue(v, def) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sameJSONValue reports whether two payload values serialize identically,
+// falling back to deep equality for values that cannot be marshaled
+func sameJSONValue(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,200 @@
+package revenium
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// registryPayloads builds metering payloads for every operation type with all
+// optional features enabled, through the same paths the middleware sends them
+func registryPayloads(t *testing.T) []map[string]interface{} {
+	t.Helper()
+	ctx := context.Background()
+	var payloads []map[string]interface{}
+
+	// Every operation, with every payload-affecting option enabled
+	runway := newFakeRunway(t)
+	runway.status = func(taskID string) *TaskStatusResponse {
+		return &TaskStatusResponse{
+			ID:       taskID,
+			Status:   TaskStatusSucceeded,
+			Output:   []string{"https://cdn.example.com/" + taskID + "-1.mp4", "https://cdn.example.com/" + taskID + "-2.mp4"},
+			Metadata: map[string]interface{}{"fps": 24.0},
+		}
+	}
+	metering := newFakeMetering(t)
+	r := newTestClient(t, runway, metering,
+		WithMeteringRequired(true),
+		WithCapturePrompts(true),
+		WithPromptMetrics(true),
+		WithByteCounts(true),
+		WithAppVersion("1.2.3"),
+		WithCorrelationIDGenerator(func() string { return "corr-1" }),
+		WithLifecycleMetering(true),
+		WithPerOutputMetering(true),
+		WithCustomNamespace(true),
+		WithBillingBasis("gen4_turbo", BillingBasisPerGeneration),
+		WithPricingTable(map[string]ModelPricing{
+			"gen4_turbo": {CreditsPerGeneration: 50, CostPerCredit: 0.01, Currency: "USD"},
+		}),
+	)
+	retry := 1
+	score := 0.9
+	seed := 42
+	watermark := false
+	metadata := &UsageMetadata{
+		OrganizationID:        "org",
+		ProductID:             "product",
+		TaskType:              "trailer",
+		Agent:                 "worker-1",
+		SubscriptionID:        "sub",
+		TraceID:               "trace",
+		ParentTransactionID:   "parent",
+		TraceType:             "batch",
+		TraceName:             "nightly",
+		Environment:           "production",
+		Region:                "us-east-1",
+		RetryNumber:           &retry,
+		OriginalTransactionID: "first-attempt",
+		CredentialAlias:       "primary",
+		Subscriber:            map[string]interface{}{"id": "user-1"},
+		TaskID:                "job-7",
+		ResponseQualityScore:  &score,
+		VideoJobID:            "video-job",
+		AudioJobID:            "audio-job",
+		Custom:                map[string]interface{}{"campaignId": "spring"},
+	}
+	longPrompt := strings.Repeat("a", MaxPromptLength+1)
+	operations := []func() error{
+		func() error {
+			_, err := r.ImageToVideo(ctx, &ImageToVideoRequest{
+				PromptImage: "https://example.com/frame.png", PromptText: longPrompt,
+				Model: "gen4_turbo", Duration: 10, Ratio: "1280:720", Seed: &seed, Watermark: &watermark,
+			}, metadata)
+			return err
+		},
+		func() error {
+			_, err := r.VideoToVideo(ctx, &VideoToVideoRequest{PromptVideo: "https://example.com/in.mp4", PromptText: "noir"}, metadata)
+			return err
+		},
+		func() error {
+			_, err := r.TextToVideo(ctx, &TextToVideoRequest{PromptText: "a storm at sea", Ratio: "768:1280"}, metadata)
+			return err
+		},
+		func() error {
+			_, err := r.UpscaleVideo(ctx, &VideoUpscaleRequest{PromptVideo: "https://example.com/in.mp4"}, metadata)
+			return err
+		},
+	}
+	for _, run := range operations {
+		if err := run(); err != nil {
+			t.Fatalf("operation error = %v", err)
+		}
+	}
+	if err := r.WaitForMetering(ctx); err != nil {
+		t.Fatalf("WaitForMetering() error = %v", err)
+	}
+	payloads = append(payloads, metering.received()...)
+
+	// Canceled task
+	canceledRunway := newFakeRunway(t)
+	canceledRunway.status = func(taskID string) *TaskStatusResponse {
+		return &TaskStatusResponse{ID: taskID, Status: TaskStatusCanceled}
+	}
+	canceledMetering := newFakeMetering(t)
+	canceled := newTestClient(t, canceledRunway, canceledMetering, WithMeteringRequired(true))
+	canceled.TextToVideo(ctx, &TextToVideoRequest{PromptText: "abandoned"}, nil)
+	payloads = append(payloads, canceledMetering.received()...)
+
+	// Failed task reporting a frame count, built directly
+	m := NewMeteringClient(testConfig())
+	reason, code := "content moderation", "SAFETY.INPUT.TEXT"
+	payloads = append(payloads, m.BuildMeteringPayload(&VideoGenerationResult{
+		ID:          "task-failed",
+		Status:      TaskStatusFailed,
+		Model:       "gen4_turbo",
+		Error:       &reason,
+		FailureCode: &code,
+		FrameCount:  120,
+		PollCount:   3,
+		Metadata:    map[string]interface{}{"operationSubtype": OperationTextToVideo, "requestedDuration": 5},
+	}, nil))
+
+	// Payload trimmed to the size limit
+	trimCfg := testConfig()
+	trimCfg.MaxPayloadBytes = 2048
+	trimCfg.CapturePrompts = true
+	trimClient := NewMeteringClient(trimCfg)
+	large := trimClient.BuildMeteringPayload(&VideoGenerationResult{
+		ID:       "task-large",
+		Status:   TaskStatusSucceeded,
+		Metadata: map[string]interface{}{"_capturedPrompt": strings.Repeat("b", 4096)},
+	}, nil)
+	data, err := trimClient.marshalPayload(large)
+	if err != nil {
+		t.Fatalf("marshalPayload() error = %v", err)
+	}
+	if data, err = trimClient.fitPayload(large, data); err != nil {
+		t.Fatalf("fitPayload() error = %v", err)
+	}
+	var trimmed map[string]interface{}
+	if err := json.Unmarshal(data, &trimmed); err != nil {
+		t.Fatalf("unmarshal trimmed payload: %v", err)
+	}
+	payloads = append(payloads, trimmed)
+
+	return payloads
+}
+
+// TestPayloadFieldsMatchRegistry keeps meteringPayloadFields in sync with
+// buildMeteringPayload: every emitted key must be registered, and every
+// registered field must be emitted by at least one payload
+func TestPayloadFieldsMatchRegistry(t *testing.T) {
+	emitted := make(map[string]bool)
+	for _, payload := range registryPayloads(t) {
+		for key := range payload {
+			emitted[key] = true
+			if !IsReservedPayloadKey(key) {
+				t.Errorf("payload key %q is not registered in meteringPayloadFields", key)
+			}
+		}
+	}
+
+	var missing []string
+	for _, f := range meteringPayloadFields {
+		if !emitted[f.Name] {
+			missing = append(missing, f.Name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("registered fields never emitted: %s", strings.Join(missing, ", "))
+	}
+}
+
+func TestRequiredFieldsPresentForEveryOperation(t *testing.T) {
+	for _, payload := range registryPayloads(t) {
+		if err := validatePayload(payload); err != nil {
+			t.Errorf("payload %v: %v", payload["transactionId"], err)
+		}
+	}
+}
+
+func TestMeteringPayloadSchemaListsRegistry(t *testing.T) {
+	var schema struct {
+		Properties   map[string]json.RawMessage `json:"properties"`
+		ReservedKeys []string                   `json:"x-reservedKeys"`
+	}
+	if err := json.Unmarshal(MeteringPayloadSchema(), &schema); err != nil {
+		t.Fatalf("unmarshal schema: %v", err)
+	}
+	if len(schema.Properties) != len(meteringPayloadFields) {
+		t.Errorf("schema has %d properties, registry has %d fields", len(schema.Properties), len(meteringPayloadFields))
+	}
+	if len(schema.ReservedKeys) != len(meteringPayloadFields) {
+		t.Errorf("schema lists %d reserved keys, registry has %d fields", len(schema.ReservedKeys), len(meteringPayloadFields))
+	}
+}
@@ -1,6 +1,10 @@
 package revenium
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // TaskStatus represents the status of a Runway task
 type TaskStatus string
@@ -13,64 +17,293 @@ const (
 	TaskStatusCanceled  TaskStatus = "CANCELED"
 )
 
+// BillingBasis describes how the Revenium backend should price a metering record
+type BillingBasis string
+
+const (
+	// BillingBasisPerSecond bills by durationSeconds (the default for Runway video models)
+	BillingBasisPerSecond BillingBasis = "PER_SECOND"
+	// BillingBasisPerGeneration bills a flat price per task; the backend should use
+	// quantity (always 1) and treat durationSeconds as informational only
+	BillingBasisPerGeneration BillingBasis = "PER_GENERATION"
+)
+
+// Operation names, as reported in ModelInfo.Operations and Capabilities
+const (
+	OperationImageToVideo = "image-to-video"
+	OperationVideoToVideo = "video-to-video"
+	OperationVideoUpscale = "video-upscale"
+	OperationTextToVideo  = "text-to-video"
+)
+
+// KeyframePosition is the position of a keyframe image within the generated video
+type KeyframePosition string
+
+const (
+	KeyframePositionFirst KeyframePosition = "first"
+	KeyframePositionLast  KeyframePosition = "last"
+)
+
+// KeyframeImage is one keyframe of a multi-image image-to-video request
+type KeyframeImage struct {
+	URI      string           `json:"uri"`      // Image URL or data URI
+	Position KeyframePosition `json:"position"` // Where the frame appears in the video
+}
+
+// ModelInfo describes a Runway model and the features it supports
+type ModelInfo struct {
+	ID                string             `json:"id"`                          // Model identifier sent to Runway
+	Operations        []string           `json:"operations,omitempty"`        // Supported operations (e.g. "image-to-video")
+	Durations         []int              `json:"durations,omitempty"`         // Supported output durations in seconds
+	KeyframePositions []KeyframePosition `json:"keyframePositions,omitempty"` // Supported keyframe positions for image-to-video
+	SupportsSeed      bool               `json:"supportsSeed"`                // Whether Seed is honored
+	SupportsWatermark bool               `json:"supportsWatermark"`           // Whether Watermark can be controlled
+}
+
+// staticModels is the built-in model registry, used for validation and as the
+// fallback when Runway's model list cannot be fetched
+var staticModels = []ModelInfo{
+	{
+		ID:                "gen3a_turbo",
+		Operations:        []string{OperationImageToVideo, OperationVideoToVideo, OperationTextToVideo},
+		Durations:         []int{5, 10},
+		KeyframePositions: []KeyframePosition{KeyframePositionFirst, KeyframePositionLast},
+		SupportsSeed:      true,
+		SupportsWatermark: true,
+	},
+	{
+		ID:                "gen4_turbo",
+		Operations:        []string{OperationImageToVideo},
+		Durations:         []int{5, 10},
+		KeyframePositions: []KeyframePosition{KeyframePositionFirst},
+		SupportsSeed:      true,
+	},
+	{
+		ID:         "upscale",
+		Operations: []string{OperationVideoUpscale},
+	},
+}
+
+// StaticModels returns a copy of the built-in model registry
+func StaticModels() []ModelInfo {
+	models := make([]ModelInfo, len(staticModels))
+	copy(models, staticModels)
+	return models
+}
+
+// lookupStaticModel returns the registry entry for a model ID
+func lookupStaticModel(id string) (ModelInfo, bool) {
+	for _, model := range staticModels {
+		if model.ID == id {
+			return model, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
 // ImageToVideoRequest represents a request to create an image-to-video task
 type ImageToVideoRequest struct {
-	PromptImage string  `json:"promptImage"`           // Base64 encoded image or URL
-	PromptText  string  `json:"promptText,omitempty"`  // Optional text prompt
-	Model       string  `json:"model,omitempty"`       // Model version (default: gen3a_turbo)
-	Duration    int     `json:"duration,omitempty"`    // Duration in seconds (5 or 10)
-	Ratio       string  `json:"ratio,omitempty"`       // Resolution ratio (e.g., "1280:768", "768:1280")
-	Seed        *int    `json:"seed,omitempty"`        // Random seed for reproducibility
-	Watermark   *bool   `json:"watermark,omitempty"`   // Whether to include watermark
+	PromptImage  string          `json:"promptImage"`          // Base64 encoded image or URL
+	PromptImages []KeyframeImage `json:"-"`                    // Keyframe images; when set, sent as promptImage instead of PromptImage
+	PromptText   string          `json:"promptText,omitempty"` // Optional text prompt
+	Model        string          `json:"model,omitempty"`      // Model version (default: gen3a_turbo)
+	Duration     int             `json:"duration,omitempty"`   // Duration in seconds (5 or 10)
+	Ratio        string          `json:"ratio,omitempty"`      // Resolution ratio (e.g., "1280:768", "768:1280")
+	Seed         *int            `json:"seed,omitempty"`       // Random seed for reproducibility
+	Watermark    *bool           `json:"watermark,omitempty"`  // Whether to include watermark
+	// ClientToken is sent as the Idempotency-Key header so a retried submission
+	// can be recognized as the same task. With WithRunwayIdempotency it is
+	// derived from the request content when empty and stored here, so resubmitting
+	// this request, or an identical one, reuses it.
+	ClientToken string `json:"-"`
+	// ExtraParams are additional body fields for model-specific Runway
+	// parameters (e.g. a negative prompt). They never override the fields above.
+	ExtraParams map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON sends PromptImages as the promptImage array when keyframes are
+// set and merges ExtraParams into the body
+func (r ImageToVideoRequest) MarshalJSON() ([]byte, error) {
+	type alias ImageToVideoRequest
+	var data []byte
+	var err error
+	if len(r.PromptImages) == 0 {
+		data, err = json.Marshal(alias(r))
+	} else {
+		data, err = json.Marshal(struct {
+			alias
+			PromptImage []KeyframeImage `json:"promptImage"`
+		}{alias(r), r.PromptImages})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraParams(data, r.ExtraParams)
+}
+
+// NegativePromptKeys are the ExtraParams keys recognized as a negative prompt
+// for prompt capture
+var NegativePromptKeys = []string{"negativePromptText", "negativePrompt", "negative_prompt"}
+
+// negativePrompt returns the negative prompt carried in ExtraParams, if any
+func negativePrompt(extra map[string]interface{}) string {
+	for _, key := range NegativePromptKeys {
+		if text, ok := extra[key].(string); ok && text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// mergeExtraParams adds extra fields to a marshaled JSON object, keeping the
+// object's own fields when keys collide
+func mergeExtraParams(data []byte, extra map[string]interface{}) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, exists := body[k]; !exists {
+			body[k] = v
+		}
+	}
+	return json.Marshal(body)
+}
+
+// validateKeyframes checks that keyframe positions are valid, unique and
+// supported by the request's model
+func (r *ImageToVideoRequest) validateKeyframes() error {
+	if len(r.PromptImages) == 0 {
+		return nil
+	}
+
+	model, known := lookupStaticModel(r.Model)
+	allowed := model.KeyframePositions
+	seen := make(map[KeyframePosition]bool, len(r.PromptImages))
+	for i, frame := range r.PromptImages {
+		if frame.URI == "" {
+			return NewValidationError(fmt.Sprintf("keyframe %d has no URI", i), nil)
+		}
+		if frame.Position != KeyframePositionFirst && frame.Position != KeyframePositionLast {
+			return NewValidationError(fmt.Sprintf("keyframe %d has invalid position %q", i, frame.Position), nil)
+		}
+		if seen[frame.Position] {
+			return NewValidationError(fmt.Sprintf("duplicate keyframe position %q", frame.Position), nil)
+		}
+		seen[frame.Position] = true
+
+		if known && !containsPosition(allowed, frame.Position) {
+			return NewValidationError(fmt.Sprintf("model %s does not support keyframe position %q", r.Model, frame.Position), nil).
+				WithDetails("model", r.Model)
+		}
+	}
+
+	return nil
+}
+
+// containsPosition reports whether positions includes p
+func containsPosition(positions []KeyframePosition, p KeyframePosition) bool {
+	for _, pos := range positions {
+		if pos == p {
+			return true
+		}
+	}
+	return false
 }
 
 // VideoToVideoRequest represents a request to create a video-to-video task
 type VideoToVideoRequest struct {
-	PromptVideo string  `json:"promptVideo"`           // Base64 encoded video or URL
-	PromptText  string  `json:"promptText,omitempty"`  // Optional text prompt
-	Model       string  `json:"model,omitempty"`       // Model version
-	Duration    int     `json:"duration,omitempty"`    // Duration in seconds
-	Seed        *int    `json:"seed,omitempty"`        // Random seed for reproducibility
-	Watermark   *bool   `json:"watermark,omitempty"`   // Whether to include watermark
+	PromptVideo string `json:"promptVideo"`          // Base64 encoded video or URL
+	PromptText  string `json:"promptText,omitempty"` // Optional text prompt
+	Model       string `json:"model,omitempty"`      // Model version
+	Duration    int    `json:"duration,omitempty"`   // Duration in seconds
+	Seed        *int   `json:"seed,omitempty"`       // Random seed for reproducibility
+	Watermark   *bool  `json:"watermark,omitempty"`  // Whether to include watermark
+	// ClientToken is sent as the Idempotency-Key header so a retried submission
+	// can be recognized as the same task. With WithRunwayIdempotency it is
+	// derived from the request content when empty and stored here, so resubmitting
+	// this request, or an identical one, reuses it.
+	ClientToken string `json:"-"`
+	// ExtraParams are additional body fields for model-specific Runway
+	// parameters (e.g. a negative prompt). They never override the fields above.
+	ExtraParams map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON merges ExtraParams into the request body
+func (r VideoToVideoRequest) MarshalJSON() ([]byte, error) {
+	type alias VideoToVideoRequest
+	data, err := json.Marshal(alias(r))
+	if err != nil {
+		return nil, err
+	}
+	return mergeExtraParams(data, r.ExtraParams)
+}
+
+// TextToVideoRequest represents a request to generate video from a text prompt alone
+type TextToVideoRequest struct {
+	PromptText  string `json:"promptText"`          // Text prompt
+	Model       string `json:"model,omitempty"`     // Model version (default: gen3a_turbo)
+	Duration    int    `json:"duration,omitempty"`  // Duration in seconds
+	Ratio       string `json:"ratio,omitempty"`     // Resolution ratio (e.g., "1280:768")
+	Seed        *int   `json:"seed,omitempty"`      // Random seed for reproducibility
+	Watermark   *bool  `json:"watermark,omitempty"` // Whether to include watermark
+	ClientToken string `json:"-"`                   // Sent as the Idempotency-Key header (see ImageToVideoRequest.ClientToken)
 }
 
 // VideoUpscaleRequest represents a request to upscale a video
 type VideoUpscaleRequest struct {
-	PromptVideo string `json:"promptVideo"`           // Base64 encoded video or URL
-	Model       string `json:"model,omitempty"`       // Upscale model version
+	PromptVideo           string  `json:"promptVideo"`     // Base64 encoded video or URL
+	Model                 string  `json:"model,omitempty"` // Upscale model version
+	SourceDurationSeconds float64 `json:"-"`               // Source video duration for metering (not sent to Runway)
+	ClientToken           string  `json:"-"`               // Sent as the Idempotency-Key header (see ImageToVideoRequest.ClientToken)
 }
 
 // TaskResponse represents the response when creating a task
 type TaskResponse struct {
-	ID     string     `json:"id"`               // Task ID
-	Status TaskStatus `json:"status"`           // Current status
-	Error  *string    `json:"error,omitempty"`  // Error message if failed
+	ID     string     `json:"id"`              // Task ID
+	Status TaskStatus `json:"status"`          // Current status
+	Error  *string    `json:"error,omitempty"` // Error message if failed
 }
 
 // TaskStatusResponse represents the response when polling task status
 type TaskStatusResponse struct {
-	ID               string                 `json:"id"`                        // Task ID
-	Status           TaskStatus             `json:"status"`                    // Current status
-	Progress         *float64               `json:"progress,omitempty"`        // Progress percentage (0-100)
-	Output           []string               `json:"output,omitempty"`          // Output URLs when complete
-	Error            *string                `json:"error,omitempty"`           // Error message if failed
-	CreatedAt        time.Time              `json:"createdAt"`                 // Task creation time
-	UpdatedAt        *time.Time             `json:"updatedAt,omitempty"`       // Last update time
-	FailureCode      *string                `json:"failureCode,omitempty"`     // Failure code if failed
-	FailureMessage   *string                `json:"failureMessage,omitempty"`  // Failure message if failed
-	Metadata         map[string]interface{} `json:"metadata,omitempty"`        // Additional metadata
+	ID             string                 `json:"id"`                       // Task ID
+	Status         TaskStatus             `json:"status"`                   // Current status
+	Progress       *float64               `json:"progress,omitempty"`       // Progress percentage (0-100)
+	Output         []string               `json:"output,omitempty"`         // Output URLs when complete
+	Error          *string                `json:"error,omitempty"`          // Error message if failed
+	CreatedAt      time.Time              `json:"createdAt"`                // Task creation time
+	UpdatedAt      *time.Time             `json:"updatedAt,omitempty"`      // Last update time
+	FailureCode    *string                `json:"failureCode,omitempty"`    // Failure code if failed
+	FailureMessage *string                `json:"failureMessage,omitempty"` // Failure message if failed
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`       // Additional metadata
+	Raw            json.RawMessage        `json:"-"`                        // Untouched response body
 }
 
 // VideoGenerationResult contains the final result of a video generation task
 type VideoGenerationResult struct {
-	ID               string                 `json:"id"`                        // Task ID
-	Status           TaskStatus             `json:"status"`                    // Final status
-	OutputURLs       []string               `json:"outputUrls"`                // Generated video URLs
-	Duration         time.Duration          `json:"duration"`                  // Total time taken
-	Model            string                 `json:"model"`                     // Model used
-	Error            *string                `json:"error,omitempty"`           // Error if failed
-	FailureCode      *string                `json:"failureCode,omitempty"`     // Failure code if failed
-	Metadata         map[string]interface{} `json:"metadata,omitempty"`        // Request metadata
+	ID                string                 `json:"id"`                          // Task ID
+	Status            TaskStatus             `json:"status"`                      // Final status
+	OutputURLs        []string               `json:"outputUrls"`                  // Generated video URLs
+	Duration          time.Duration          `json:"duration"`                    // Total time taken
+	Model             string                 `json:"model"`                       // Model used
+	Error             *string                `json:"error,omitempty"`             // Error if failed
+	FailureCode       *string                `json:"failureCode,omitempty"`       // Failure code if failed
+	RequestBytes      int64                  `json:"requestBytes"`                // Total request body bytes sent to Runway
+	ResponseBytes     int64                  `json:"responseBytes"`               // Total response body bytes received from Runway
+	RawStatus         json.RawMessage        `json:"rawStatus,omitempty"`         // Final task status response from Runway, unmodified
+	CorrelationID     string                 `json:"correlationId,omitempty"`     // Correlation ID from WithCorrelationIDGenerator
+	LocalPaths        []string               `json:"localPaths,omitempty"`        // Downloaded outputs, index-aligned with OutputURLs ("" if a download failed)
+	PollCount         int                    `json:"pollCount"`                   // Status requests made while waiting for the task
+	LastProgress      float64                `json:"lastProgress"`                // Progress reported by the last status poll
+	FPS               float64                `json:"fps,omitempty"`               // Output frame rate, when Runway reports it
+	FrameCount        int                    `json:"frameCount,omitempty"`        // Output frame count, when Runway reports it
+	RequestedDuration int                    `json:"requestedDuration,omitempty"` // Duration requested from Runway in seconds
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`          // Request metadata
 }
 
 // RunwayErrorResponse represents an error response from the Runway API
@@ -88,39 +321,124 @@ type PollingConfig struct {
 	InitialInterval time.Duration // Initial polling interval
 	MaxInterval     time.Duration // Maximum polling interval
 	Timeout         time.Duration // Overall timeout
+	// FixedInterval polls every InitialInterval instead of backing off 1.5x per
+	// attempt. This gives more responsive status updates at the cost of more
+	// Runway API calls; MaxAttempts and Timeout still apply, so raise
+	// MaxAttempts when using a short interval with a long Timeout.
+	FixedInterval bool
+	// OnFailure classifies a FAILED task. Returning true marks the failure as
+	// transient (e.g. an internal Runway error rather than a safety or input
+	// rejection): the task error matches IsRetryableTaskFailure, the failed
+	// attempt is metered, and the synchronous generation methods resubmit the
+	// request with an incremented RetryNumber. nil never resubmits.
+	OnFailure func(status *TaskStatusResponse) bool
+	// MaxResubmits bounds resubmissions after retryable failures (default:
+	// DefaultMaxResubmits when OnFailure is set)
+	MaxResubmits int
+}
+
+// DefaultMaxResubmits is the number of resubmissions after retryable failures
+// when PollingConfig.MaxResubmits is not set
+const DefaultMaxResubmits = 1
+
+// maxResubmits returns how many times a request may be resubmitted after
+// retryable failures
+func (c *PollingConfig) maxResubmits() int {
+	if c.OnFailure == nil {
+		return 0
+	}
+	if c.MaxResubmits <= 0 {
+		return DefaultMaxResubmits
+	}
+	return c.MaxResubmits
 }
 
 // DefaultPollingConfig returns the default polling configuration
 func DefaultPollingConfig() *PollingConfig {
 	return &PollingConfig{
-		MaxAttempts:     180,                // 180 attempts (30 min at 10s intervals)
-		InitialInterval: 2 * time.Second,    // Start with 2 seconds
-		MaxInterval:     10 * time.Second,   // Max 10 seconds between polls
-		Timeout:         30 * time.Minute,   // 30 minute total timeout (allows for queue delays)
+		MaxAttempts:     180,              // 180 attempts (30 min at 10s intervals)
+		InitialInterval: 2 * time.Second,  // Start with 2 seconds
+		MaxInterval:     10 * time.Second, // Max 10 seconds between polls
+		Timeout:         30 * time.Minute, // 30 minute total timeout (allows for queue delays)
 	}
 }
 
 // UsageMetadata represents metadata to be sent with metering data
 type UsageMetadata struct {
-	OrganizationID       string                 `json:"organizationId,omitempty"`
-	ProductID            string                 `json:"productId,omitempty"`
-	TaskType             string                 `json:"taskType,omitempty"`
-	Agent                string                 `json:"agent,omitempty"`
-	SubscriptionID       string                 `json:"subscriptionId,omitempty"`
-	TraceID              string                 `json:"traceId,omitempty"`
-	// Distributed tracing fields
-	ParentTransactionID  string                 `json:"parentTransactionId,omitempty"`
-	TraceType            string                 `json:"traceType,omitempty"`
-	TraceName            string                 `json:"traceName,omitempty"`
-	Environment          string                 `json:"environment,omitempty"`
-	Region               string                 `json:"region,omitempty"`
-	RetryNumber          *int                   `json:"retryNumber,omitempty"`
-	CredentialAlias      string                 `json:"credentialAlias,omitempty"`
-	Subscriber           map[string]interface{} `json:"subscriber,omitempty"`
-	TaskID               string                 `json:"taskId,omitempty"`
-	ResponseQualityScore *float64               `json:"responseQualityScore,omitempty"`
+	OrganizationID string `json:"organizationId,omitempty"`
+	ProductID      string `json:"productId,omitempty"`
+	TaskType       string `json:"taskType,omitempty"`
+	Agent          string `json:"agent,omitempty"`
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	TraceID        string `json:"traceId,omitempty"`
+	// Distributed tracing fields. With WithPerOutputMetering, per-output records
+	// use the task ID as parentTransactionId instead of ParentTransactionID.
+	ParentTransactionID string `json:"parentTransactionId,omitempty"`
+	TraceType           string `json:"traceType,omitempty"`
+	TraceName           string `json:"traceName,omitempty"`
+	Environment         string `json:"environment,omitempty"`
+	Region              string `json:"region,omitempty"`
+	RetryNumber         *int   `json:"retryNumber,omitempty"`
+	// OriginalTransactionID links a retry to the transactionId of the first
+	// attempt. It is only emitted when RetryNumber > 0, letting the backend
+	// collapse retries into one logical usage. Each attempt still creates its own
+	// Runway task, so transactionId (and any idempotency key derived from it)
+	// differs per attempt; this field is the grouping key, not a dedup key.
+	OriginalTransactionID string                 `json:"originalTransactionId,omitempty"`
+	CredentialAlias       string                 `json:"credentialAlias,omitempty"`
+	Subscriber            map[string]interface{} `json:"subscriber,omitempty"`
+	TaskID                string                 `json:"taskId,omitempty"`
+	ResponseQualityScore  *float64               `json:"responseQualityScore,omitempty"`
 	// Multimodal job identifiers
-	VideoJobID           string                 `json:"videoJobId,omitempty"`
-	AudioJobID           string                 `json:"audioJobId,omitempty"`
-	Custom               map[string]interface{} `json:"custom,omitempty"`
+	VideoJobID string                 `json:"videoJobId,omitempty"`
+	AudioJobID string                 `json:"audioJobId,omitempty"`
+	Custom     map[string]interface{} `json:"custom,omitempty"`
+	// CapturePrompts overrides WithCapturePrompts for this call: true captures
+	// the prompt even when capture is off, false suppresses it even when on.
+	// When nil, the configured setting applies. It is not sent in the payload.
+	CapturePrompts *bool `json:"-"`
+	// MeteringBaseURL routes this record to a different Revenium instance
+	// (e.g. per tenant). It is normalized with NormalizeReveniumBaseURL and is
+	// not sent in the payload. When empty, the configured base URL is used.
+	MeteringBaseURL string `json:"-"`
+}
+
+// Capabilities reports what this middleware version supports and which
+// optional features are enabled in the client's configuration
+type Capabilities struct {
+	Version    string   `json:"version"`    // Middleware version
+	Operations []string `json:"operations"` // Supported generation operations
+
+	// Library features (fixed for a given version)
+	AsyncTasks  bool `json:"asyncTasks"`  // Start* methods returning a TaskHandle
+	TextToVideo bool `json:"textToVideo"` // Text-only video generation
+
+	// Configured features
+	ScheduledMetering      bool `json:"scheduledMetering"`      // Records are batched and sent on a schedule
+	MeteringRequired       bool `json:"meteringRequired"`       // Metering failures are returned to the caller
+	PerOutputMetering      bool `json:"perOutputMetering"`      // One record per output
+	MeteringCircuitBreaker bool `json:"meteringCircuitBreaker"` // Metering fast-fails while Revenium is down
+	CapturePrompts         bool `json:"capturePrompts"`         // Prompts are sent with metering
+	ModelCapabilityCheck   bool `json:"modelCapabilityCheck"`   // Requests are validated against the model registry
+	ConcurrencyLimits      bool `json:"concurrencyLimits"`      // Concurrent tasks are limited
+	OutputDurationProbing  bool `json:"outputDurationProbing"`  // Output videos are probed for their duration
+	RequestLogging         bool `json:"requestLogging"`         // HTTP calls are access-logged
+}
+
+// ServiceCheck reports the outcome of a single connectivity check
+type ServiceCheck struct {
+	OK      bool          `json:"ok"`      // Whether the service accepted the credentials
+	Latency time.Duration `json:"latency"` // Round-trip time of the check
+	Error   error         `json:"-"`       // Error returned by the check, if any
+}
+
+// VerifyResult contains the per-service outcome of ReveniumRunway.Verify
+type VerifyResult struct {
+	Runway   ServiceCheck `json:"runway"`   // Runway API key and connectivity
+	Revenium ServiceCheck `json:"revenium"` // Revenium connectivity; key rejections only when the health endpoint reports them
+}
+
+// OK returns true when both services were reachable and neither rejected its key
+func (v *VerifyResult) OK() bool {
+	return v.Runway.OK && v.Revenium.OK
 }
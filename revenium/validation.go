@@ -0,0 +1,381 @@
+package revenium
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ValidateRequest checks a generation request against the capabilities of its
+// model in the static registry (StaticModels). It accepts *ImageToVideoRequest,
+// *VideoToVideoRequest, *TextToVideoRequest and *VideoUpscaleRequest and returns a ValidationError
+// naming the first unsupported feature. Models missing from the registry are
+// not checked, so new Runway models are never rejected outright.
+func ValidateRequest(req interface{}) error {
+	switch r := req.(type) {
+	case *ImageToVideoRequest:
+		if err := r.validateKeyframes(); err != nil {
+			return err
+		}
+		return checkCapabilities(OperationImageToVideo, r.Model, r.Duration, r.Seed, r.Watermark)
+	case *VideoToVideoRequest:
+		return checkCapabilities(OperationVideoToVideo, r.Model, r.Duration, r.Seed, r.Watermark)
+	case *TextToVideoRequest:
+		return checkCapabilities(OperationTextToVideo, r.Model, r.Duration, r.Seed, r.Watermark)
+	case *VideoUpscaleRequest:
+		return checkCapabilities(OperationVideoUpscale, r.Model, 0, nil, nil)
+	default:
+		return NewValidationError(fmt.Sprintf("unsupported request type %T", req), nil)
+	}
+}
+
+// checkCapabilities validates the features requested for an operation against
+// the model's registry entry
+func checkCapabilities(operation, modelID string, duration int, seed *int, watermark *bool) error {
+	model, known := lookupStaticModel(modelID)
+	if !known {
+		Debug("Model %s is not in the registry; skipping capability check", modelID)
+		return nil
+	}
+
+	if len(model.Operations) > 0 && !containsString(model.Operations, operation) {
+		return capabilityError(modelID, fmt.Sprintf("does not support %s", operation))
+	}
+	if duration > 0 && len(model.Durations) > 0 && !containsInt(model.Durations, duration) {
+		return capabilityError(modelID, fmt.Sprintf("does not support a %ds duration (supported: %v)", duration, model.Durations))
+	}
+	if seed != nil && !model.SupportsSeed {
+		return capabilityError(modelID, "does not support seed control")
+	}
+	if watermark != nil && !model.SupportsWatermark {
+		return capabilityError(modelID, "does not support watermark control")
+	}
+
+	return nil
+}
+
+// capabilityError builds the ValidationError for an unsupported model feature
+func capabilityError(modelID, problem string) *ReveniumError {
+	return NewValidationError(fmt.Sprintf("model %s %s", modelID, problem), nil).
+		WithDetails("model", modelID)
+}
+
+// containsString reports whether values includes v
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt reports whether values includes v
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Default request values applied by the request constructors
+const (
+	DefaultVideoModel   = "gen3a_turbo"
+	DefaultUpscaleModel = "upscale"
+	DefaultDuration     = 5
+)
+
+// ReqOption is a functional option for the request constructors
+type ReqOption func(*requestOptions)
+
+// requestOptions collects ReqOption values before they are applied to a request
+type requestOptions struct {
+	promptText *string
+	model      *string
+	duration   *int
+	ratio      *string
+	seed       *int
+	watermark  *bool
+	maxImage   int64
+}
+
+// WithPromptText sets the text prompt (image-to-video and video-to-video)
+func WithPromptText(text string) ReqOption {
+	return func(o *requestOptions) {
+		o.promptText = &text
+	}
+}
+
+// WithModel sets the Runway model
+func WithModel(model string) ReqOption {
+	return func(o *requestOptions) {
+		o.model = &model
+	}
+}
+
+// WithDuration sets the output duration in seconds (image-to-video, video-to-video and text-to-video)
+func WithDuration(seconds int) ReqOption {
+	return func(o *requestOptions) {
+		o.duration = &seconds
+	}
+}
+
+// WithRatio sets the output resolution ratio, e.g. "1280:768" (image-to-video and text-to-video)
+func WithRatio(ratio string) ReqOption {
+	return func(o *requestOptions) {
+		o.ratio = &ratio
+	}
+}
+
+// WithSeed sets the random seed (image-to-video, video-to-video and text-to-video)
+func WithSeed(seed int) ReqOption {
+	return func(o *requestOptions) {
+		o.seed = &seed
+	}
+}
+
+// WithWatermark controls the watermark (image-to-video, video-to-video and text-to-video)
+func WithWatermark(watermark bool) ReqOption {
+	return func(o *requestOptions) {
+		o.watermark = &watermark
+	}
+}
+
+// WithImageSizeLimit sets the data URI size limit checked by
+// NewImageToVideoRequestFromBytes (default DefaultMaxImageDataURIBytes). Pass
+// the same value as the client's WithMaxImageBytes when raising it.
+func WithImageSizeLimit(n int64) ReqOption {
+	return func(o *requestOptions) {
+		o.maxImage = n
+	}
+}
+
+// NewImageToVideoRequest builds a validated image-to-video request with defaults
+// applied (model gen3a_turbo, 5 second duration). It returns a ValidationError
+// for missing or invalid fields and for features the model does not support.
+func NewImageToVideoRequest(promptImage string, opts ...ReqOption) (*ImageToVideoRequest, error) {
+	if promptImage == "" {
+		return nil, NewValidationError("prompt image is required", nil)
+	}
+
+	o := applyReqOptions(opts)
+	req := &ImageToVideoRequest{
+		PromptImage: promptImage,
+		Model:       DefaultVideoModel,
+		Duration:    DefaultDuration,
+		Seed:        o.seed,
+		Watermark:   o.watermark,
+	}
+	if o.promptText != nil {
+		req.PromptText = *o.promptText
+	}
+	if o.model != nil {
+		req.Model = *o.model
+	}
+	if o.duration != nil {
+		req.Duration = *o.duration
+	}
+	if o.ratio != nil {
+		if !isValidRatio(*o.ratio) {
+			return nil, NewValidationError(fmt.Sprintf("invalid ratio %q, expected WIDTH:HEIGHT", *o.ratio), nil)
+		}
+		req.Ratio = *o.ratio
+	}
+
+	if err := validateCommon(req.Model, req.Duration); err != nil {
+		return nil, err
+	}
+	if err := ValidateRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Runway's documented size limits for image inputs, used when
+// WithMaxImageBytes is not set
+const (
+	DefaultMaxImageDataURIBytes = 5 * 1024 * 1024  // Encoded data URI length
+	DefaultMaxImageURLBytes     = 16 * 1024 * 1024 // Image behind a URL
+)
+
+// supportedImageTypes are the image content types Runway accepts as data URIs
+var supportedImageTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// NewImageToVideoRequestFromBytes builds a validated image-to-video request from
+// in-memory image data, encoded as a base64 data URI. An empty contentType is
+// detected from the data. It returns a ValidationError for empty data, for data
+// over the size limit (see WithImageSizeLimit) and for unsupported content types.
+func NewImageToVideoRequestFromBytes(data []byte, contentType, promptText string, opts ...ReqOption) (*ImageToVideoRequest, error) {
+	if len(data) == 0 {
+		return nil, NewValidationError("image data is empty", nil)
+	}
+
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	if !containsString(supportedImageTypes, contentType) {
+		return nil, NewValidationError(fmt.Sprintf("unsupported image content type %q", contentType), nil).
+			WithDetails("supported", supportedImageTypes)
+	}
+
+	limit := int64(DefaultMaxImageDataURIBytes)
+	if o := applyReqOptions(opts); o.maxImage > 0 {
+		limit = o.maxImage
+	}
+	dataURI := "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	if size := int64(len(dataURI)); size > limit {
+		return nil, imageSizeError(size, limit)
+	}
+	return NewImageToVideoRequest(dataURI, append([]ReqOption{WithPromptText(promptText)}, opts...)...)
+}
+
+// imageSizeError reports an image input larger than Runway accepts
+func imageSizeError(size, limit int64) error {
+	return NewValidationError(fmt.Sprintf("image is %d bytes, maximum is %d", size, limit), nil).
+		WithDetails("size", size).
+		WithDetails("maxSize", limit)
+}
+
+// NewVideoToVideoRequest builds a validated video-to-video request with defaults
+// applied (model gen3a_turbo, 5 second duration)
+func NewVideoToVideoRequest(promptVideo string, opts ...ReqOption) (*VideoToVideoRequest, error) {
+	if promptVideo == "" {
+		return nil, NewValidationError("prompt video is required", nil)
+	}
+
+	o := applyReqOptions(opts)
+	if o.ratio != nil {
+		return nil, NewValidationError("ratio is not supported for video-to-video", nil)
+	}
+
+	req := &VideoToVideoRequest{
+		PromptVideo: promptVideo,
+		Model:       DefaultVideoModel,
+		Duration:    DefaultDuration,
+		Seed:        o.seed,
+		Watermark:   o.watermark,
+	}
+	if o.promptText != nil {
+		req.PromptText = *o.promptText
+	}
+	if o.model != nil {
+		req.Model = *o.model
+	}
+	if o.duration != nil {
+		req.Duration = *o.duration
+	}
+
+	if err := validateCommon(req.Model, req.Duration); err != nil {
+		return nil, err
+	}
+	if err := ValidateRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// NewTextToVideoRequest builds a validated text-to-video request with defaults
+// applied (model gen3a_turbo, 5 second duration). WithPromptText is ignored;
+// the prompt is the promptText argument.
+func NewTextToVideoRequest(promptText string, opts ...ReqOption) (*TextToVideoRequest, error) {
+	if promptText == "" {
+		return nil, NewValidationError("prompt text is required", nil)
+	}
+
+	o := applyReqOptions(opts)
+	req := &TextToVideoRequest{
+		PromptText: promptText,
+		Model:      DefaultVideoModel,
+		Duration:   DefaultDuration,
+		Seed:       o.seed,
+		Watermark:  o.watermark,
+	}
+	if o.model != nil {
+		req.Model = *o.model
+	}
+	if o.duration != nil {
+		req.Duration = *o.duration
+	}
+	if o.ratio != nil {
+		if !isValidRatio(*o.ratio) {
+			return nil, NewValidationError(fmt.Sprintf("invalid ratio %q, expected WIDTH:HEIGHT", *o.ratio), nil)
+		}
+		req.Ratio = *o.ratio
+	}
+
+	if err := validateCommon(req.Model, req.Duration); err != nil {
+		return nil, err
+	}
+	if err := ValidateRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// NewVideoUpscaleRequest builds a validated video upscale request with the
+// default upscale model applied. Only WithModel applies to upscaling.
+func NewVideoUpscaleRequest(promptVideo string, opts ...ReqOption) (*VideoUpscaleRequest, error) {
+	if promptVideo == "" {
+		return nil, NewValidationError("prompt video is required", nil)
+	}
+
+	o := applyReqOptions(opts)
+	if o.promptText != nil || o.duration != nil || o.ratio != nil || o.seed != nil || o.watermark != nil {
+		return nil, NewValidationError("video upscale only supports the model option", nil)
+	}
+
+	req := &VideoUpscaleRequest{
+		PromptVideo: promptVideo,
+		Model:       DefaultUpscaleModel,
+	}
+	if o.model != nil {
+		req.Model = *o.model
+	}
+
+	if req.Model == "" {
+		return nil, NewValidationError("model cannot be empty", nil)
+	}
+	if err := ValidateRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// applyReqOptions collects opts into a requestOptions
+func applyReqOptions(opts []ReqOption) *requestOptions {
+	o := &requestOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// validateCommon checks fields shared by the generation requests
+func validateCommon(model string, duration int) error {
+	if model == "" {
+		return NewValidationError("model cannot be empty", nil)
+	}
+	if duration <= 0 {
+		return NewValidationError(fmt.Sprintf("invalid duration %d, must be positive", duration), nil)
+	}
+	return nil
+}
+
+// isValidRatio reports whether ratio has the WIDTH:HEIGHT form with positive integers
+func isValidRatio(ratio string) bool {
+	parts := strings.Split(ratio, ":")
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if n, err := strconv.Atoi(part); err != nil || n <= 0 {
+			return false
+		}
+	}
+	return true
+}
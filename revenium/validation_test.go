@@ -0,0 +1,49 @@
+package revenium
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pngBytes returns n bytes of data detected as image/png
+func pngBytes(n int) []byte {
+	data := make([]byte, n)
+	copy(data, "\x89PNG\r\n\x1a\n")
+	return data
+}
+
+func TestNewImageToVideoRequestFromBytesSizeLimit(t *testing.T) {
+	large := pngBytes(4 * 1024 * 1024) // Over the default limit once base64-encoded
+
+	tests := []struct {
+		name    string
+		data    []byte
+		opts    []ReqOption
+		wantErr bool
+	}{
+		{"small image, default limit", pngBytes(1024), nil, false},
+		{"large image, default limit", large, nil, true},
+		{"large image, raised limit", large, []ReqOption{WithImageSizeLimit(8 * 1024 * 1024)}, false},
+		{"small image, lowered limit", pngBytes(1024), []ReqOption{WithImageSizeLimit(512)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewImageToVideoRequestFromBytes(tt.data, "", "a dog running", tt.opts...)
+			if tt.wantErr {
+				if !IsValidationError(err) {
+					t.Fatalf("error = %v, want a ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if !bytes.HasPrefix([]byte(req.PromptImage), []byte("data:image/png;base64,")) {
+				t.Errorf("PromptImage prefix = %.30q, want a PNG data URI", req.PromptImage)
+			}
+			if req.PromptText != "a dog running" {
+				t.Errorf("PromptText = %q, want %q", req.PromptText, "a dog running")
+			}
+		})
+	}
+}
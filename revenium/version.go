@@ -46,6 +46,13 @@ func GetMiddlewareSource() string {
 	return middlewareSourceVal
 }
 
+// resetMiddlewareSource clears the cached middleware source so the next
+// GetMiddlewareSource call detects the version again
+func resetMiddlewareSource() {
+	middlewareSourceOnce = sync.Once{}
+	middlewareSourceVal = ""
+}
+
 // GetVersion returns just the version string
 func GetVersion() string {
 	version := DefaultVersion